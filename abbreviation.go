@@ -0,0 +1,112 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// An Abbreviation is an acronym like API. Its first use within a document is expanded, like API (Application
+// Programming Interface), and each further use just shows the short form. Latex outputs use \ac of the acronym
+// package instead, which expands the first use by itself.
+type Abbreviation struct {
+	Short string
+	Long  string
+	First bool // First is not serialized but calculated by MarkAbbreviations
+}
+
+// Abbr creates a new abbreviation
+func Abbr(short, long string) *Abbreviation {
+	return &Abbreviation{Short: short, Long: long}
+}
+
+// Expanded returns the short form followed by the long one in parentheses
+func (a *Abbreviation) Expanded() string {
+	if a.Long == "" {
+		return a.Short
+	}
+	return a.Short + " (" + a.Long + ")"
+}
+
+// Text returns the expanded form for the first use and the short form otherwise
+func (a *Abbreviation) Text() string {
+	if a.First {
+		return a.Expanded()
+	}
+	return a.Short
+}
+
+func (a *Abbreviation) Type() string {
+	return AbbreviationType
+}
+
+func (a *Abbreviation) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = a.Type()
+	m["short"] = a.Short
+	optSet(m, "long", a.Long)
+	return m
+}
+
+func (a *Abbreviation) fromJson(m map[string]interface{}) {
+	a.Short = optString(m, "short")
+	a.Long = optString(m, "long")
+}
+
+// latexAcronymKey returns the short form as key of the acronym package, which must not contain special characters
+func latexAcronymKey(short string) string {
+	sb := &strings.Builder{}
+	for _, r := range short {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			sb.WriteRune(r)
+		} else {
+			fmt.Fprintf(sb, "x%X", r)
+		}
+	}
+	return sb.String()
+}
+
+// latexAcronym returns the definition of the acronym package for the abbreviation
+func latexAcronym(a *Abbreviation) string {
+	return fmt.Sprintf("\\acrodef{%s}[%s]{%s}", latexAcronymKey(a.Short), EscapeLatex(a.Short), EscapeLatex(a.Long))
+}
+
+// MarkAbbreviations sets First for the first use of each short form per document, in document order. A root which
+// is neither a workspace nor a document is treated like a single document.
+func MarkAbbreviations(root Discriminator) {
+	var scopes []Discriminator
+	if w, ok := root.(*Workspace); ok {
+		for _, doc := range w.Documents() {
+			scopes = append(scopes, doc)
+		}
+	} else {
+		scopes = append(scopes, root)
+	}
+	for _, scope := range scopes {
+		seen := make(map[string]bool)
+		Walk(scope, func(d Discriminator) bool {
+			if a, ok := d.(*Abbreviation); ok {
+				a.First = !seen[a.Short]
+				seen[a.Short] = true
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAbbreviation(t *testing.T) {
+	abbr := Abbr("API", "Application Programming Interface")
+	if got := roundTrip(t, abbr); !reflect.DeepEqual(abbr, got) {
+		t.Fatalf("expected %+v but got %+v", abbr, got)
+	}
+
+	ws := &Workspace{}
+	doc := ws.NewDocument()
+	doc.NewChapter("intro").Add(Text("The "), Abbr("API", "Application Programming Interface"), Text(" of the "),
+		Abbr("API", "Application Programming Interface"), Text(" server."))
+	other := ws.NewDocument()
+	other.Add(Abbr("API", "Application Programming Interface"))
+
+	MarkAbbreviations(ws)
+	if got := PlainText(doc); got != "The API (Application Programming Interface) of the API server." {
+		t.Fatal(got)
+	}
+	if !other.Body[0].(*Abbreviation).First {
+		t.Fatal("expected the first use of each document to be expanded")
+	}
+	want := `<abbr title="Application Programming Interface">API</abbr> (Application Programming Interface) of the ` +
+		`<abbr title="Application Programming Interface">API</abbr> server.`
+	if got := renderHTML(doc.Body[0]); got != "<section>\n<h2>intro</h2>\n<p>The "+want+"</p>\n</section>\n" {
+		t.Fatal(got)
+	}
+	if got := renderLatex(doc.Body[0].(*Chapter).Body[3]); got != `\ac{API}` {
+		t.Fatal(got)
+	}
+	want = "\\usepackage{acronym}\n\\acrodef{API}[API]{Application Programming Interface}"
+	if got := RequiredPreamble(doc); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+	if got := latexAcronym(Abbr("C++", "C plus plus")); got != `\acrodef{Cx2Bx2B}[C++]{C plus plus}` {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// The alignments of an Align element
+const (
+	AlignLeft    = "left"
+	AlignCenter  = "center"
+	AlignRight   = "right"
+	AlignJustify = "justify"
+)
+
+// An Align element sets the alignment of the paragraphs in its body. An empty Alignment inherits the alignment of
+// the parent.
+type Align struct {
+	Alignment string // Alignment is one of AlignLeft, AlignCenter, AlignRight or AlignJustify
+	Body      []Discriminator
+}
+
+// Centered creates a centered body
+func Centered(body ...Discriminator) *Align {
+	return &Align{Alignment: AlignCenter, Body: body}
+}
+
+func (a *Align) Add(body ...Discriminator) *Align {
+	a.Body = append(a.Body, body...)
+	return a
+}
+
+func (a *Align) Type() string {
+	return AlignType
+}
+
+func (a *Align) children() []Discriminator {
+	return a.Body
+}
+
+func (a *Align) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = a.Type()
+	optSet(m, "alignment", a.Alignment)
+	m["body"] = toJson(a.Body)
+	return m
+}
+
+func (a *Align) fromJson(m map[string]interface{}) {
+	a.Alignment = optString(m, "alignment")
+	a.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		a.Body = append(a.Body, fromJson(obj))
+	}
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAlign(t *testing.T) {
+	align := Centered(Text("centered"), &Align{Alignment: AlignRight, Body: []Discriminator{Text("right")}})
+	if res := roundTrip(t, align); !reflect.DeepEqual(align, res) {
+		t.Fatalf("expected %+v but got %+v", align, res)
+	}
+
+	want := "<div style=\"text-align: center\">\n<p>centered</p>\n" +
+		"<div style=\"text-align: right\">\n<p>right</p>\n</div>\n</div>\n"
+	if got := renderHTML(align); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+	want = "\\begin{center}\ncentered\n\n\\begin{flushright}\nright\n\n\\end{flushright}\n\n\\end{center}\n\n"
+	if got := renderLatex(align); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+	if got := renderHTML(&Align{Body: []Discriminator{Text("inherited")}}); got != "<p>inherited</p>\n" {
+		t.Fatal(got)
+	}
+
+	justified := &Align{Alignment: AlignJustify, Body: []Discriminator{Text("justified")}}
+	want = "\\begin{justify}\njustified\n\n\\end{justify}\n\n"
+	if got := renderLatex(justified); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+	if got := RequiredPreamble(Centered(justified)); got != `\usepackage{ragged2e}` {
+		t.Fatalf("unexpected preamble %q", got)
+	}
+}
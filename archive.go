@@ -0,0 +1,121 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The supported formats of Build.ApplyToArchive
+const (
+	ArchiveTar = "tar"
+	ArchiveZip = "zip"
+)
+
+// ApplyToArchive applies all rules like BuildToMemory and streams the generated files into a tar or zip archive.
+// The entries keep their relative paths and file modes.
+func (b *Build) ApplyToArchive(w io.Writer, format string) error {
+	var write func(dir string) error
+	switch format {
+	case ArchiveTar:
+		write = func(dir string) error {
+			return writeTar(w, dir)
+		}
+	case ArchiveZip:
+		write = func(dir string) error {
+			return writeZip(w, dir)
+		}
+	default:
+		return fmt.Errorf("unsupported archive format '%s'", format)
+	}
+	return b.applyToTmpDir(context.Background(), write)
+}
+
+// walkArchiveFiles calls f for each file of the dir with its slash separated relative path
+func walkArchiveFiles(dir string, f func(name string, path string, info os.FileInfo) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk path %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return f(filepath.ToSlash(rel), path, info)
+	})
+}
+
+func writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := walkArchiveFiles(dir, func(name string, path string, info os.FileInfo) error {
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return fmt.Errorf("cannot create tar header for %s: %w", name, err)
+		}
+		hdr.Name = name
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("cannot write tar header for %s: %w", name, err)
+		}
+		return copyArchiveFile(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+	err := walkArchiveFiles(dir, func(name string, path string, info os.FileInfo) error {
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return fmt.Errorf("cannot create zip header for %s: %w", name, err)
+		}
+		hdr.Name = name
+		hdr.Method = zip.Deflate
+		out, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("cannot write zip header for %s: %w", name, err)
+		}
+		return copyArchiveFile(out, path)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func copyArchiveFile(w io.Writer, fname string) error {
+	in, err := os.Open(fname)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", fname, err)
+	}
+	defer in.Close()
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", fname, err)
+	}
+	return nil
+}
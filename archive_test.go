@@ -0,0 +1,88 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestApplyToArchive(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"index.html.gohtml": "{{.Title}}", "css/style.css": "body{}"})
+	build, err := NewBuild(&Workspace{Title: "archive"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.FileMode = 0600
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+
+	buf := &bytes.Buffer{}
+	if err := build.ApplyToArchive(buf, ArchiveZip); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[f.Name] = string(b)
+		if f.Mode() != os.FileMode(0600) {
+			t.Fatalf("expected mode 0600 of %s but got %v", f.Name, f.Mode())
+		}
+	}
+	if len(entries) != 2 || entries["site/index.html"] != "archive" || entries["site/css/style.css"] != "body{}" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	buf.Reset()
+	if err := build.ApplyToArchive(buf, ArchiveTar); err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(buf)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if len(names) != 2 || names[0] != "site/css/style.css" || names[1] != "site/index.html" {
+		t.Fatalf("unexpected tar entries %v", names)
+	}
+
+	if err := build.ApplyToArchive(buf, "rar"); err == nil {
+		t.Fatal("expected unsupported format to fail")
+	}
+}
@@ -0,0 +1,324 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// asciidocEscaper replaces the characters of a Span, which have a meaning in AsciiDoc, by attribute references
+// or escapes them.
+var asciidocEscaper = strings.NewReplacer(
+	`\`, "{backslash}",
+	"*", "{asterisk}",
+	"^", "{caret}",
+	"~", "{tilde}",
+	"`", "{backtick}",
+	"+", "{plus}",
+	"[", "{startsb}",
+	"]", "{endsb}",
+	"<", "{lt}",
+	">", "{gt}",
+	"{", `\{`,
+	"_", `\_`,
+	"#", `\#`,
+)
+
+// ToAsciiDoc exports the document as AsciiDoc. Chapters become section titles by their level, code becomes a
+// source block with the Hint as language and Spans are escaped. Elements without an AsciiDoc representation are
+// exported as plain text.
+func (c *Document) ToAsciiDoc() ([]byte, error) {
+	r := &asciidocRenderer{}
+	// the author line is only recognized after a title, so a document without title has no header at all
+	if c.Title != "" {
+		r.printf("= %s\n", asciidocEscaper.Replace(c.Title))
+		var authors []string
+		for _, a := range c.Authors {
+			name := strings.TrimSpace(a.Firstname + " " + a.Lastname)
+			if a.EMail != "" {
+				name += " <" + a.EMail + ">"
+			}
+			authors = append(authors, name)
+		}
+		if len(authors) > 0 {
+			r.printf("%s\n", strings.Join(authors, "; "))
+		}
+	}
+	if len(Collect(c, TOCType)) > 0 {
+		r.printf(":toc: macro\n")
+	}
+	if r.sb.Len() > 0 {
+		r.printf("\n")
+	}
+	r.renderBlocks(c.Body)
+	return []byte(strings.TrimRight(r.sb.String(), "\n") + "\n"), nil
+}
+
+type asciidocRenderer struct {
+	sb        strings.Builder
+	listDepth int
+}
+
+func (r *asciidocRenderer) printf(format string, args ...interface{}) {
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+}
+
+// renderBlocks writes consecutive inline elements as a paragraph and separates all blocks by an empty line
+func (r *asciidocRenderer) renderBlocks(body []Discriminator) {
+	open := false
+	closeParagraph := func() {
+		if open {
+			r.printf("\n\n")
+			open = false
+		}
+	}
+	for _, e := range body {
+		switch {
+		case is(e, ParagraphBreakType):
+			closeParagraph()
+		case isBlock(e):
+			closeParagraph()
+			r.renderBlock(e)
+		default:
+			open = true
+			r.renderInline(e)
+		}
+	}
+	closeParagraph()
+}
+
+func (r *asciidocRenderer) renderBlock(d Discriminator) {
+	switch t := d.(type) {
+	case *Part:
+		if t.Title != "" {
+			r.printf("= %s\n\n", asciidocEscaper.Replace(t.Title))
+		}
+		r.renderBlocks(t.Body)
+	case *Chapter:
+		r.printf("%s %s\n\n", strings.Repeat("=", t.Level+2), asciidocEscaper.Replace(t.Title))
+		r.renderBlocks(t.Body)
+	case *Code:
+		if t.Id != "" {
+			r.printf("[[%s]]\n", t.Id)
+		}
+		if t.Caption != "" {
+			r.printf(".%s\n", asciidocEscaper.Replace(t.Caption))
+		}
+		markers := t.calloutMarkers()
+		r.printf("[source,%s]\n----\n", t.Hint)
+		for i, line := range t.VisibleLines() {
+			r.printf("%s", line)
+			if n, ok := markers[i+1]; ok {
+				r.printf(" <%d>", n)
+			}
+			r.printf("\n")
+		}
+		r.printf("----\n")
+		for i, line := range t.CalloutLines() {
+			r.printf("<%d> %s\n", i+1, asciidocEscaper.Replace(t.Callouts[line]))
+		}
+		r.printf("\n")
+	case *Diff:
+		r.printf("[source,diff]\n----\n")
+		for _, line := range t.Lines {
+			r.printf("%s%s\n", line.Prefix(), line.Text)
+		}
+		r.printf("----\n\n")
+	case *Embed:
+		r.printf("%s[]\n\n", t.URL)
+	case *RevisionHistory:
+		r.printf("<<<\n\n[options=\"header\"]\n|===\n|Version |Date |Author |Changes\n")
+		for _, rev := range t.Entries {
+			r.printf("|%s |%s |%s |%s\n", asciidocCell(rev.Version), asciidocCell(rev.Date), asciidocCell(rev.Author), asciidocCell(rev.Summary))
+		}
+		r.printf("|===\n\n")
+	case *Table:
+		r.renderTable(t)
+	case *Image:
+		var attrs []string
+		if t.Width != "" {
+			attrs = append(attrs, "width="+t.Width)
+		}
+		if t.Height != "" {
+			attrs = append(attrs, "height="+t.Height)
+		}
+		r.printf("image::%s[%s]\n\n", t.Src, strings.Join(attrs, ","))
+	case *List:
+		r.renderList(t)
+		if r.listDepth == 0 {
+			r.printf("\n")
+		}
+	case *Align:
+		if t.Alignment != "" {
+			r.printf("[.text-%s]\n--\n", t.Alignment)
+			r.renderBlocks(t.Body)
+			r.printf("--\n\n")
+			return
+		}
+		r.renderBlocks(t.Body)
+	case *Titlepage:
+		if t.Cover != nil {
+			r.printf("image::%s[]\n\n", t.Cover.Src)
+		}
+		r.renderBlocks(t.Body)
+	case *defaultBody:
+		r.renderBlocks(t.Body)
+	default:
+		switch d.Type() {
+		case TOCType:
+			r.printf("toc::[]\n\n")
+		case NewpageType:
+			r.printf("<<<\n\n")
+		case AppendixType:
+		default:
+			r.printf("%s\n\n", asciidocEscaper.Replace(PlainText(d)))
+		}
+	}
+}
+
+func (r *asciidocRenderer) renderList(l *List) {
+	marker := "*"
+	if l.Ordered {
+		marker = "."
+	}
+	r.listDepth++
+	prefix := strings.Repeat(marker, r.listDepth)
+	for _, item := range l.Items {
+		r.printf("%s ", prefix)
+		entry, ok := item.(*ListEntry)
+		if !ok {
+			r.renderInline(item)
+			r.printf("\n")
+			continue
+		}
+		if entry.IsTask() {
+			if entry.IsChecked() {
+				r.printf("[x] ")
+			} else {
+				r.printf("[ ] ")
+			}
+		}
+		var nested []*List
+		for _, e := range entry.Body {
+			if list, ok := e.(*List); ok {
+				nested = append(nested, list)
+				continue
+			}
+			r.renderInline(e)
+		}
+		r.printf("\n")
+		for _, list := range nested {
+			r.renderList(list)
+		}
+	}
+	r.listDepth--
+}
+
+func (r *asciidocRenderer) renderInline(d Discriminator) {
+	switch t := d.(type) {
+	case *Span:
+		r.printf("%s", asciidocEscaper.Replace(t.Value))
+	case *Image:
+		r.printf("image:%s[]", t.Src)
+	case *Attachment:
+		r.printf("link:%s[%s]", t.OutputPath(), asciidocEscaper.Replace(t.DisplayLabel()))
+	case *Abbreviation:
+		r.printf("%s", asciidocEscaper.Replace(t.Text()))
+	case *Hyperlink:
+		switch {
+		case t.Href == "":
+			r.renderGroup("", t.Body, "")
+		case len(t.Body) == 0:
+			r.printf("%s[]", t.Href)
+		default:
+			r.renderGroup("link:"+t.Href+"[", t.Body, "]")
+		}
+	case *Tooltip:
+		r.renderGroup("", t.Body, "")
+		if t.Note != "" {
+			r.printf("footnote:[%s]", strings.ReplaceAll(asciidocEscaper.Replace(t.Note), "]", `\]`))
+		}
+	case *MarginNote:
+		r.renderGroup(" (", t.Body, ")")
+	case *defaultBody:
+		switch t.Type() {
+		case BoldType:
+			r.renderGroup("**", t.Body, "**")
+		case ItalicType:
+			r.renderGroup("__", t.Body, "__")
+		case UnderlineType:
+			r.renderGroup("[.underline]##", t.Body, "##")
+		default:
+			r.renderGroup("", t.Body, "")
+		}
+	default:
+		switch d.Type() {
+		case LineBreakType:
+			r.printf(" +\n")
+		default:
+			r.printf("%s", asciidocEscaper.Replace(PlainText(d)))
+		}
+	}
+}
+
+func (r *asciidocRenderer) renderGroup(open string, body []Discriminator, close string) {
+	r.printf("%s", open)
+	for _, e := range body {
+		r.renderInline(e)
+	}
+	r.printf("%s", close)
+}
+
+// asciidocColumns are the column specifiers by alignment
+var asciidocColumns = map[string]string{
+	AlignLeft:   "<",
+	AlignCenter: "^",
+	AlignRight:  ">",
+}
+
+func (r *asciidocRenderer) renderTable(t *Table) {
+	if t.Caption != "" {
+		r.printf(".%s\n", asciidocEscaper.Replace(t.Caption))
+	}
+	var cols []string
+	for i := 0; i < t.Columns(); i++ {
+		cols = append(cols, asciidocColumns[t.ColumnAlign(i)])
+	}
+	r.printf("[cols=\"%s\"", strings.Join(cols, ","))
+	if len(t.Header) > 0 {
+		r.printf(",options=\"header\"")
+	}
+	r.printf("]\n|===\n")
+	for _, row := range t.AllRows() {
+		var cells []string
+		for _, cell := range row.Cells {
+			sub := &asciidocRenderer{}
+			for _, e := range cell.Body {
+				sub.renderInline(e)
+			}
+			cells = append(cells, "|"+strings.ReplaceAll(sub.sb.String(), "|", `\|`))
+		}
+		r.printf("%s\n", strings.Join(cells, " "))
+	}
+	r.printf("|===\n\n")
+}
+
+// asciidocCell escapes the text of a table cell
+func asciidocCell(str string) string {
+	return strings.ReplaceAll(asciidocEscaper.Replace(str), "|", `\|`)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"testing"
+)
+
+func TestToAsciiDoc(t *testing.T) {
+	doc := &Document{Title: "Book", Authors: []*Author{{Firstname: "Ada", Lastname: "Lovelace"}}}
+	chap := doc.NewChapter("Intro")
+	chap.Add(Text("a *b* "), Bold(Text("bold")), LineBreak(), Italic(Text("it")))
+	chap.NewChapter("Details").Add(&Code{Hint: "go", Caption: "main", Lines: []string{"func main() {", "}"}})
+	chap.Add(UnorderedList(ListItem(Text("one"), OrderedList(ListItem(Text("nested")))), Task(true, Text("done"))))
+	chap.Add(&Image{Src: "a.png", Width: "50%"})
+
+	b, err := doc.ToAsciiDoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `= Book
+Ada Lovelace
+
+== Intro
+
+a {asterisk}b{asterisk} **bold** +
+__it__
+
+=== Details
+
+.main
+[source,go]
+----
+func main() {
+}
+----
+
+* one
+.. nested
+* [x] done
+
+image::a.png[width=50%]
+`
+	if string(b) != want {
+		t.Fatalf("expected\n%s\nbut got\n%s", want, string(b))
+	}
+
+	untitled := &Document{Authors: doc.Authors}
+	untitled.Add(&Part{Body: []Discriminator{Text("text")}})
+	b, err = untitled.ToAsciiDoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "text\n" {
+		t.Fatalf("expected no header but got\n%s", string(b))
+	}
+}
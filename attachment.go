@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// An Attachment is an inline link to a downloadable file, like a sample config. A local Src is resolved against the
+// asset dir of the build and copied into the output, see OutputPath. Urls are linked as is.
+type Attachment struct {
+	Src      string
+	Label    string // Label is the link text, the default is the file name
+	MimeType string // MimeType is optional, e.g. application/json
+}
+
+func (a *Attachment) Type() string {
+	return AttachmentType
+}
+
+// OutputPath returns the slash separated path of the copied file, relative to the output. It is the relative
+// Src, but absolute paths or paths outside of the asset dir are flattened to their file name. Urls are returned
+// as is.
+func (a *Attachment) OutputPath() string {
+	if isUrl(a.Src) {
+		return a.Src
+	}
+	clean := filepath.Clean(a.Src)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return filepath.Base(clean)
+	}
+	return filepath.ToSlash(clean)
+}
+
+// DisplayLabel returns the Label or the file name as fallback
+func (a *Attachment) DisplayLabel() string {
+	if a.Label != "" {
+		return a.Label
+	}
+	return filepath.Base(a.Src)
+}
+
+func (a *Attachment) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = a.Type()
+	m["src"] = a.Src
+	optSet(m, "label", a.Label)
+	optSet(m, "mimeType", a.MimeType)
+	return m
+}
+
+func (a *Attachment) fromJson(m map[string]interface{}) {
+	a.Src = optString(m, "src")
+	a.Label = optString(m, "label")
+	a.MimeType = optString(m, "mimeType")
+}
+
+// attachmentFile returns the local file of the attachment or the empty string for urls
+func attachmentFile(a *Attachment, assetDir string) string {
+	if a.Src == "" || isUrl(a.Src) {
+		return ""
+	}
+	if filepath.IsAbs(a.Src) {
+		return a.Src
+	}
+	return filepath.Join(assetDir, a.Src)
+}
+
+// CheckAttachments returns an error for each local attachment, which cannot be found relative to the asset dir.
+// Attachments with an url are skipped.
+func CheckAttachments(root Discriminator, assetDir string) []error {
+	var res []error
+	walkPath(root, func(d Discriminator, path string) {
+		a, ok := d.(*Attachment)
+		if !ok {
+			return
+		}
+		fname := attachmentFile(a, assetDir)
+		if fname == "" {
+			return
+		}
+		if _, err := os.Stat(fname); err != nil {
+			res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("missing attachment '%s'", a.Src)})
+		}
+	})
+	return res
+}
+
+// collectAttachments returns the local files of all attachments by their OutputPath
+func collectAttachments(root Discriminator, assetDir string) map[string]string {
+	res := make(map[string]string)
+	Walk(root, func(d Discriminator) bool {
+		if a, ok := d.(*Attachment); ok {
+			if fname := attachmentFile(a, assetDir); fname != "" {
+				res[a.OutputPath()] = fname
+			}
+		}
+		return true
+	})
+	return res
+}
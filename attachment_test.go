@@ -0,0 +1,66 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAttachment(t *testing.T) {
+	a := &Attachment{Src: "samples/config.json", Label: "sample config", MimeType: "application/json"}
+	if got := roundTrip(t, a); !reflect.DeepEqual(a, got) {
+		t.Fatalf("expected %+v but got %+v", a, got)
+	}
+	for src, want := range map[string]string{"a/b.txt": "a/b.txt", "/tmp/b.txt": "b.txt", "../b.txt": "b.txt", "https://x.org/b": "https://x.org/b"} {
+		if got := (&Attachment{Src: src}).OutputPath(); got != want {
+			t.Fatalf("expected %s but got %s", want, got)
+		}
+	}
+
+	assetDir := writeFiles(t, map[string]string{"samples/config.json": `{"debug": true}`})
+	tplDir := writeFiles(t, map[string]string{"index.txt.tmpl": "{{renderHTML .}}"})
+	ws := &Workspace{}
+	doc := ws.NewDocument()
+	doc.Id = "doc"
+	doc.Add(Text("download the "), a)
+
+	build, err := NewBuild(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AssetDir = assetDir
+	build.AddRule(&BuildRule{Id: "doc", Template: tplDir, Name: "out"})
+	files, err := build.BuildToMemory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(files["out/samples/config.json"]); got != `{"debug": true}` {
+		t.Fatalf("expected the copied attachment but got %v", files)
+	}
+	link := `<a class="attachment" href="samples/config.json" download type="application/json">sample config</a>`
+	if got := string(files["out/index.txt"]); !strings.Contains(got, link) {
+		t.Fatal(got)
+	}
+
+	doc.Add(&Attachment{Src: "missing.csv"})
+	if _, err := build.BuildToMemory(context.Background()); err == nil || !strings.Contains(err.Error(), "missing attachment 'missing.csv'") {
+		t.Fatalf("expected a missing attachment but got %v", err)
+	}
+}
@@ -0,0 +1,362 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// binaryVersion is the first byte of each binary encoded workspace
+const binaryVersion = 1
+
+// value tags of the binary encoding
+const (
+	binNull byte = iota
+	binFalse
+	binTrue
+	binInt
+	binFloat
+	binString
+	binArray
+	binObject
+)
+
+var errBinaryTruncated = errors.New("truncated binary workspace")
+
+// MarshalBinary encodes the workspace into a compact binary representation of the same structure as Marshal.
+// All strings, like keys and type names, are stored only once in a string table and referenced by index.
+func (w *Workspace) MarshalBinary() ([]byte, error) {
+	e := &binEncoder{index: make(map[string]int)}
+	root := w.toJson()
+	if err := e.collect(root); err != nil {
+		return nil, err
+	}
+	e.buf.WriteByte(binaryVersion)
+	e.uvarint(uint64(len(e.strings)))
+	for _, s := range e.strings {
+		e.uvarint(uint64(len(s)))
+		e.buf.WriteString(s)
+	}
+	e.value(root)
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a workspace from the MarshalBinary representation and replaces the current content.
+func (w *Workspace) UnmarshalBinary(data []byte) error {
+	d := &binDecoder{data: data}
+	version, err := d.byte()
+	if err != nil {
+		return err
+	}
+	if version != binaryVersion {
+		return fmt.Errorf("unsupported binary workspace version %d", version)
+	}
+	n, err := d.uvarint()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		l, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		if l > uint64(len(d.data)-d.pos) {
+			return errBinaryTruncated
+		}
+		d.strings = append(d.strings, string(d.data[d.pos:d.pos+int(l)]))
+		d.pos += int(l)
+	}
+	v, err := d.value()
+	if err != nil {
+		return err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("binary workspace is not an object")
+	}
+	if err := checkMarkup(m); err != nil {
+		return err
+	}
+	*w = Workspace{}
+	w.fromJson(m)
+	return nil
+}
+
+type binEncoder struct {
+	buf     bytes.Buffer
+	strings []string
+	index   map[string]int
+}
+
+func (e *binEncoder) uvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	e.buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+}
+
+func (e *binEncoder) str(s string) {
+	if _, ok := e.index[s]; !ok {
+		e.index[s] = len(e.strings)
+		e.strings = append(e.strings, s)
+	}
+}
+
+// collect fills the string table and fails for values, which have no json representation
+func (e *binEncoder) collect(v interface{}) error {
+	switch t := v.(type) {
+	case nil, bool, int, int64, float64:
+	case string:
+		e.str(t)
+	case []string:
+		for _, s := range t {
+			e.str(s)
+		}
+	case map[string]string:
+		for _, k := range sortedKeys(t) {
+			e.str(k)
+			e.str(t[k])
+		}
+	case []interface{}:
+		for _, i := range t {
+			if err := e.collect(i); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(t) {
+			e.str(k)
+			if err := e.collect(t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported binary value type %T", v)
+	}
+	return nil
+}
+
+// value writes the value like json would decode it, e.g. a []string becomes an array and an int becomes a number
+func (e *binEncoder) value(v interface{}) {
+	switch t := v.(type) {
+	case nil:
+		e.buf.WriteByte(binNull)
+	case bool:
+		if t {
+			e.buf.WriteByte(binTrue)
+		} else {
+			e.buf.WriteByte(binFalse)
+		}
+	case int:
+		e.number(float64(t))
+	case int64:
+		e.number(float64(t))
+	case float64:
+		e.number(t)
+	case string:
+		e.buf.WriteByte(binString)
+		e.uvarint(uint64(e.index[t]))
+	case []string:
+		if t == nil {
+			e.buf.WriteByte(binNull)
+			return
+		}
+		e.buf.WriteByte(binArray)
+		e.uvarint(uint64(len(t)))
+		for _, s := range t {
+			e.value(s)
+		}
+	case map[string]string:
+		if t == nil {
+			e.buf.WriteByte(binNull)
+			return
+		}
+		e.buf.WriteByte(binObject)
+		e.uvarint(uint64(len(t)))
+		for _, k := range sortedKeys(t) {
+			e.uvarint(uint64(e.index[k]))
+			e.value(t[k])
+		}
+	case []interface{}:
+		if t == nil {
+			e.buf.WriteByte(binNull)
+			return
+		}
+		e.buf.WriteByte(binArray)
+		e.uvarint(uint64(len(t)))
+		for _, i := range t {
+			e.value(i)
+		}
+	case map[string]interface{}:
+		if t == nil {
+			e.buf.WriteByte(binNull)
+			return
+		}
+		e.buf.WriteByte(binObject)
+		e.uvarint(uint64(len(t)))
+		for _, k := range sortedKeys(t) {
+			e.uvarint(uint64(e.index[k]))
+			e.value(t[k])
+		}
+	}
+}
+
+// number writes integral values as zig-zag varint and everything else as 8 byte float
+func (e *binEncoder) number(f float64) {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		e.buf.WriteByte(binInt)
+		var tmp [binary.MaxVarintLen64]byte
+		e.buf.Write(tmp[:binary.PutVarint(tmp[:], int64(f))])
+		return
+	}
+	e.buf.WriteByte(binFloat)
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(f))
+	e.buf.Write(tmp[:])
+}
+
+type binDecoder struct {
+	data    []byte
+	pos     int
+	strings []string
+}
+
+func (d *binDecoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errBinaryTruncated
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *binDecoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		return 0, errBinaryTruncated
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *binDecoder) str() (string, error) {
+	i, err := d.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if i >= uint64(len(d.strings)) {
+		return "", fmt.Errorf("invalid string index %d", i)
+	}
+	return d.strings[i], nil
+}
+
+// length reads a count of elements, which must be plausible for the remaining data
+func (d *binDecoder) length() (int, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, errBinaryTruncated
+	}
+	return int(n), nil
+}
+
+// value reads a value with the same types as json.Unmarshal would have created
+func (d *binDecoder) value() (interface{}, error) {
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case binNull:
+		return nil, nil
+	case binFalse:
+		return false, nil
+	case binTrue:
+		return true, nil
+	case binInt:
+		v, n := binary.Varint(d.data[d.pos:])
+		if n <= 0 {
+			return nil, errBinaryTruncated
+		}
+		d.pos += n
+		return float64(v), nil
+	case binFloat:
+		if len(d.data)-d.pos < 8 {
+			return nil, errBinaryTruncated
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.data[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case binString:
+		return d.str()
+	case binArray:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		res := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v)
+		}
+		return res, nil
+	case binObject:
+		n, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		res := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			k, err := d.str()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			res[k] = v
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("invalid binary value tag %d", tag)
+	}
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch t := m.(type) {
+	case map[string]string:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[string]interface{}:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
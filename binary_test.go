@@ -0,0 +1,63 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	ws := &Workspace{Format: 1, Title: "large", Version: "1.0"}
+	doc := ws.NewDocument()
+	doc.Id = "doc"
+	doc.Authors = append(doc.Authors, &Author{Firstname: "Ada", Links: map[string]string{"web": "https://example.com"}})
+	for i := 0; i < 200; i++ {
+		chap := doc.NewChapter(fmt.Sprintf("chapter %d", i))
+		chap.Add(Text("some text"), LineBreak(), Bold(Text("bold")))
+		chap.Add(&Image{Src: "img.png", Width: "0.5"}, OrderedList(Task(true, Text("done"))))
+	}
+
+	jsonBytes, err := Marshal(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin, err := ws.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bin) >= len(jsonBytes) {
+		t.Fatalf("expected binary (%d bytes) to be smaller than json (%d bytes)", len(bin), len(jsonBytes))
+	}
+
+	res := &Workspace{}
+	if err := res.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(jsonBytes, again) {
+		t.Fatalf("expected %s but got %s", jsonBytes, again)
+	}
+
+	if err := res.UnmarshalBinary(bin[:len(bin)/2]); err == nil {
+		t.Fatal("expected truncated data to fail")
+	}
+}
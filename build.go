@@ -17,6 +17,7 @@
 package wdydoc
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
@@ -25,6 +26,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 // A Build describes which workspace to build and how.
@@ -34,6 +37,126 @@ type Build struct {
 	dir       string       // dir to generate the output into
 	rules     []*BuildRule // the rules to apply the transformation on
 	tmpDir    string       // downloaded resources are put here
+	stats     *BuildStats  // stats of the last build
+	gitMutex  sync.Mutex   // protects gitSlots
+	gitSlots  chan struct{}
+
+	// artifacts are the generated files of the current build by rule name
+	artifacts map[string][]string
+
+	// InputFile is the markup file the workspace has been read from. It is optional and only used by Watch,
+	// to reload the workspace when the file changes.
+	InputFile string
+
+	// OnEvent is optional and notified about the progress of each build.
+	OnEvent func(e BuildEvent)
+
+	// Secrets are available to templates using the secret function. They are never serialized and redacted from
+	// any logged output.
+	Secrets map[string]string
+
+	// MaxCloneSize limits the size in bytes of a cloned template repository. A clone which exceeds it is aborted
+	// and removed. The default of 0 is unlimited.
+	MaxCloneSize int64
+
+	// CloneTimeout aborts and removes a clone of a template repository, which takes longer. The default of 0 waits
+	// forever.
+	CloneTimeout time.Duration
+
+	// GitConcurrency limits the amount of simultaneous git clone or pull operations, to respect the rate limits of
+	// git hosts. Further operations are queued. The default is 4.
+	GitConcurrency int
+
+	// FileMode is applied to all generated and copied files, the default is DefaultFileMode.
+	FileMode os.FileMode
+
+	// DirMode is applied to all generated and copied directories, the default is DefaultDirMode.
+	DirMode os.FileMode
+
+	// PreserveFileMode keeps the modes of the files generated by the template, when copying them into the output.
+	PreserveFileMode bool
+
+	// AssetDir is optional and used to resolve local images and attachments. If set, the build fails early for any
+	// missing image or attachment. Attachments are always copied into the output.
+	AssetDir string
+
+	// IncludeIntermediate copies the generated .tex and .log files next to the pdf of a latexmk build. These are
+	// even copied, if latexmk fails.
+	IncludeIntermediate bool
+
+	// ResolveEmbeds allows network access to resolve embeds by the oEmbed protocol, see Template.ResolveEmbeds.
+	ResolveEmbeds bool
+
+	// CodeTheme selects the colors of highlighted code by name, like github or monokai. Templates use it by the
+	// codeThemeCSS and codeThemeLatex functions. The default is DefaultCodeTheme.
+	CodeTheme string
+
+	// Incremental keeps the unchanged static files of the templates between builds, see Template.Incremental.
+	Incremental bool
+
+	// HTMLCodeCopyButton adds a copy button to the code blocks of the renderHTML template function.
+	HTMLCodeCopyButton bool
+
+	// EmitSourceMap writes a source map next to each generated file, see Template.EmitSourceMap.
+	EmitSourceMap bool
+
+	// AllowEmpty suppresses the warning for rules, whose element has no content, like a document without a body.
+	AllowEmpty bool
+
+	// StrictVariables fails the build for undefined variables, instead of rendering a visible placeholder.
+	StrictVariables bool
+
+	// CodeFormatters are applied on the lines of each code block by its hint before rendering, e.g. GoFormatter
+	// for go. Failures are logged, unless StrictCodeFormat is set.
+	CodeFormatters map[string]func(source string) (string, error)
+
+	// StrictCodeFormat fails the build, if a code block cannot be formatted.
+	StrictCodeFormat bool
+
+	// CodeValidators check each code block by its hint before rendering, e.g. GoValidator for go. Rejected code
+	// is logged, unless StrictCodeValidation is set.
+	CodeValidators map[string]func(*Code) error
+
+	// StrictCodeValidation fails the build, if a code block is rejected by its validator.
+	StrictCodeValidation bool
+
+	// TemplateIgnore contains the glob patterns of template files and folders, which are not processed. The default
+	// is DefaultIgnore.
+	TemplateIgnore []string
+
+	// PathMapper is optional and returns the slash separated destination path of a generated file or folder,
+	// relative to the output folder of a rule or target. The given src path is relative to the build folder of the
+	// template.
+	PathMapper func(src string) string
+
+	// PDFPostProcess is applied to each produced pdf file in order, before it is copied into the output, e.g. to
+	// add metadata or to compress it. See also PDFCommand.
+	PDFPostProcess []func(path string) error
+
+	// MakeTarget is the make target of templates, which are built by a Makefile instead of latexmk.
+	MakeTarget string
+}
+
+// defaultGitConcurrency is used, if Build.GitConcurrency is not set
+const defaultGitConcurrency = 4
+
+// Build event kinds, see BuildEvent.
+const (
+	EventStarted  = "started"
+	EventRuleDone = "rule-done"
+	EventFinished = "finished"
+	EventFailed   = "failed"
+	EventWarning  = "warning"
+)
+
+// A BuildEvent describes the progress of a build.
+type BuildEvent struct {
+	Kind string     // Kind is one of the Event* constants
+	Rule *BuildRule // Rule is the affected rule, if any
+	Err  error      // Err is only set for EventFailed and EventWarning
+
+	// Stats is only set for EventFinished
+	Stats *BuildStats
 }
 
 func NewBuild(w *Workspace, dir string) (*Build, error) {
@@ -48,13 +171,143 @@ func NewBuild(w *Workspace, dir string) (*Build, error) {
 	}, nil
 }
 
+// BuildWorkspace applies the rules directly on an in-memory workspace and writes the result into the output
+// folder. Downloaded templates are removed afterwards.
+func BuildWorkspace(w *Workspace, outDir string, rules ...*BuildRule) error {
+	b, err := NewBuild(w, outDir)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := os.RemoveAll(b.tmpDir)
+		if err != nil {
+			fmt.Printf("failed to remove %s: %v\n", b.tmpDir, err)
+		}
+	}()
+	for _, r := range rules {
+		b.AddRule(r)
+	}
+	return b.Apply()
+}
+
 func (b *Build) AddRule(r *BuildRule) {
 	b.rules = append(b.rules, r)
 }
 
+// Apply executes all rules, see also ApplyContext.
 func (b *Build) Apply() error {
+	return b.ApplyContext(context.Background())
+}
+
+// ApplyContext executes all rules and stops before the next rule, if the context has been cancelled.
+func (b *Build) ApplyContext(ctx context.Context) error {
+	return b.applyRules(ctx, b.rules)
+}
+
+// applyRules executes the rules, fires the events and records the stats
+func (b *Build) applyRules(ctx context.Context, rules []*BuildRule) error {
+	b.fire(BuildEvent{Kind: EventStarted})
+	start := time.Now()
+	b.stats = newBuildStats()
+	err := b.apply(ctx, rules)
+	b.stats.Total = time.Since(start)
+	if err != nil {
+		b.fire(BuildEvent{Kind: EventFailed, Err: err})
+		return err
+	}
+	b.fire(BuildEvent{Kind: EventFinished, Stats: b.stats})
+	return nil
+}
+
+// BuildToMemory applies all rules into a temporary folder and returns the generated files by their slash separated
+// path, relative to the output folder. The actual output folder of the build is not touched.
+func (b *Build) BuildToMemory(ctx context.Context) (map[string][]byte, error) {
+	var files map[string][]byte
+	err := b.applyToTmpDir(ctx, func(dir string) error {
+		var err error
+		files, err = ReadFiles(dir)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// applyToTmpDir applies all rules into a temporary folder, which is only valid while f is executed.
+func (b *Build) applyToTmpDir(ctx context.Context, f func(dir string) error) error {
+	dir, err := ioutil.TempDir("", "wdydoc-mem")
+	if err != nil {
+		return fmt.Errorf("tmp dir required: %w", err)
+	}
+	defer func() {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			fmt.Printf("failed to remove %s: %v\n", dir, err)
+		}
+	}()
+
+	outDir := b.dir
+	b.dir = dir
+	defer func() {
+		b.dir = outDir
+	}()
+
+	if err := b.ApplyContext(ctx); err != nil {
+		return err
+	}
+	return f(dir)
+}
+
+// Stats returns the timings of the last build or nil, if nothing has been build yet.
+func (b *Build) Stats() *BuildStats {
+	return b.stats
+}
+
+// warn logs a problem, which does not fail the build, and fires an EventWarning
+func (b *Build) warn(r *BuildRule, err error) {
+	fmt.Println(redact(err.Error(), b.Secrets))
+	b.fire(BuildEvent{Kind: EventWarning, Rule: r, Err: err})
+}
+
+func (b *Build) fire(e BuildEvent) {
+	if b.OnEvent != nil {
+		b.OnEvent(e)
+	}
+}
+
+// ApplyRule executes only the rule with the given name, e.g. to rebuild the output of a changed document. Other
+// outputs are not touched and already provided templates are reused, except for the rules it depends on, which
+// are applied first.
+func (b *Build) ApplyRule(ruleName string) error {
+	var rules []*BuildRule
 	for _, r := range b.rules {
+		if r.Name == ruleName {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("build does not contain rule '%s'", ruleName)
+	}
+	return b.applyRules(context.Background(), rules)
+}
+
+func (b *Build) apply(ctx context.Context, rules []*BuildRule) error {
+	if _, ok := LookupCodeTheme(b.CodeTheme); !ok {
+		return fmt.Errorf("unknown code theme '%s'", b.CodeTheme)
+	}
+	rules, err := b.orderRules(rules)
+	if err != nil {
+		return err
+	}
+	b.artifacts = make(map[string][]string)
+	for _, r := range rules {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now()
 		template, err := b.provideTemplate(r.Template)
+		b.stats.add(r.Name, PhaseProvide, time.Since(start))
 		if err != nil {
 			return fmt.Errorf("unable to provide template: %w", err)
 		}
@@ -62,59 +315,270 @@ func (b *Build) Apply() error {
 		if objRoot == nil {
 			return fmt.Errorf("workspace does not contain '%s'", r.Id)
 		}
+		if isEmpty(objRoot) && !b.AllowEmpty {
+			b.warn(r, fmt.Errorf("'%s' has no content to render", r.Id))
+		}
+		if b.AssetDir != "" {
+			if errs := CheckImages(objRoot, b.AssetDir); len(errs) > 0 {
+				return joinErrors("missing images:", errs)
+			}
+			if errs := CheckAttachments(objRoot, b.AssetDir); len(errs) > 0 {
+				return joinErrors("missing attachments:", errs)
+			}
+		}
+		prepare(objRoot)
+		if errs := ResolveVariables(objRoot, b.workspace.Variables); len(errs) > 0 && b.StrictVariables {
+			return joinErrors("undefined variables:", errs)
+		}
+		if errs := FormatCode(objRoot, b.CodeFormatters); len(errs) > 0 {
+			if b.StrictCodeFormat {
+				return joinErrors("unformattable code:", errs)
+			}
+			b.warn(r, joinErrors("unformattable code:", errs))
+		}
+		if errs := CheckCode(objRoot, b.CodeValidators); len(errs) > 0 {
+			if b.StrictCodeValidation {
+				return joinErrors("invalid code:", errs)
+			}
+			b.warn(r, joinErrors("invalid code:", errs))
+		}
 
-		tmp := sha256.Sum224([]byte(r.Id + r.Template))
-		transformTmpDir := filepath.Join(b.tmpDir, "transform", hex.EncodeToString(tmp[:]))
-
-		tpl, err := ReadTemplate(template, transformTmpDir)
-		if err != nil {
-			return fmt.Errorf("failed to read template %s: %w", template, err)
+		if len(r.Targets) == 0 {
+			if err := b.render(r, template, "", objRoot); err != nil {
+				return err
+			}
 		}
-		files, err := tpl.Build(objRoot)
-		targetDir := filepath.Join(b.dir, r.Name)
+		for _, target := range r.Targets {
+			if err := b.render(r, filepath.Join(template, target), target, objRoot); err != nil {
+				return err
+			}
+		}
+		b.fire(BuildEvent{Kind: EventRuleDone, Rule: r})
+	}
+	return nil
+}
 
-		err = os.MkdirAll(targetDir, os.ModePerm)
-		if err != nil {
-			return fmt.Errorf("mkdir %s failed: %w", targetDir, err)
+// orderRules returns the rules including the rules they depend on, so that each rule follows its dependencies.
+// Otherwise the order is kept. A cyclic or unknown dependency is an error.
+func (b *Build) orderRules(rules []*BuildRule) ([]*BuildRule, error) {
+	byName := make(map[string][]*BuildRule)
+	for _, r := range append(append([]*BuildRule(nil), b.rules...), rules...) {
+		if !containsRule(byName[r.Name], r) {
+			byName[r.Name] = append(byName[r.Name], r)
 		}
+	}
 
-		for _, f := range files {
-			dst := filepath.Join(targetDir, filepath.Base(f))
-			if IsDir(f) {
-				err := CopyDir(f, dst)
-				if err != nil {
-					return fmt.Errorf("failed to copy result folder: %w", err)
-				}
-			} else {
-				err := CopyFile(f, dst)
-				if err != nil {
-					return fmt.Errorf("failed to copy result file: %w", err)
+	const visiting, visited = 1, 2
+	state := make(map[*BuildRule]int)
+	var res []*BuildRule
+	var visit func(r *BuildRule) error
+	visit = func(r *BuildRule) error {
+		switch state[r] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("cyclic dependency of rule '%s'", r.Name)
+		}
+		state[r] = visiting
+		for _, name := range r.DependsOn {
+			deps, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("rule '%s' depends on unknown rule '%s'", r.Name, name)
+			}
+			for _, dep := range deps {
+				if err := visit(dep); err != nil {
+					return err
 				}
 			}
 		}
+		state[r] = visited
+		res = append(res, r)
+		return nil
+	}
+	for _, r := range rules {
+		if err := visit(r); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func containsRule(rules []*BuildRule, r *BuildRule) bool {
+	for _, other := range rules {
+		if other == r {
+			return true
+		}
+	}
+	return false
+}
+
+// render applies the model to a single template folder of the rule and copies the result into the rules output
+// folder. If a target is given, the result is put into a sub folder with the same name.
+func (b *Build) render(r *BuildRule, template string, target string, model Discriminator) error {
+	tmp := sha256.Sum224([]byte(r.Id + r.Template + target))
+	transformTmpDir := filepath.Join(b.tmpDir, "transform", hex.EncodeToString(tmp[:]))
+
+	start := time.Now()
+	ignore := b.TemplateIgnore
+	if ignore == nil {
+		ignore = DefaultIgnore
+	}
+	tpl, err := ReadTemplateIgnore(template, transformTmpDir, ignore)
+	b.stats.add(r.Name, PhaseParse, time.Since(start))
+	if err != nil {
+		return fmt.Errorf("failed to read template %s: %w", template, err)
+	}
+	tpl.IncludeIntermediate = b.IncludeIntermediate
+	tpl.Secrets = b.Secrets
+	tpl.FileMode = b.FileMode
+	tpl.DirMode = b.DirMode
+	tpl.MakeTarget = b.MakeTarget
+	tpl.NoAutobuild = r.SkipAutobuild
+	tpl.Assets = collectAttachments(model, b.AssetDir)
+	tpl.HTMLCodeCopyButton = b.HTMLCodeCopyButton
+	tpl.Incremental = b.Incremental
+	tpl.CodeTheme = b.CodeTheme
+	tpl.ResolveEmbeds = b.ResolveEmbeds
+	tpl.EmitSourceMap = b.EmitSourceMap
+	tpl.Deps = make(map[string][]string)
+	for _, name := range r.DependsOn {
+		tpl.Deps[name] = b.artifacts[name]
+	}
+	files, buildErr := tpl.Build(model)
+	for phase, d := range tpl.timings {
+		b.stats.add(r.Name, phase, d)
+	}
+	for _, err := range tpl.warnings {
+		b.warn(r, err)
+	}
+	if buildErr != nil && len(files) == 0 {
+		return fmt.Errorf("failed to build template %s: %w", template, buildErr)
+	}
+	if buildErr == nil {
+		if err := b.postProcess(files); err != nil {
+			return err
+		}
+	}
+
+	start = time.Now()
+	targetDir := filepath.Join(b.dir, r.Name, target)
+
+	err = os.MkdirAll(targetDir, tpl.dirMode())
+	if err != nil {
+		return fmt.Errorf("mkdir %s failed: %w", targetDir, err)
+	}
+
+	fileMode, dirMode := tpl.fileMode(), tpl.dirMode()
+	if b.PreserveFileMode {
+		fileMode, dirMode = 0, 0
+	}
+	for _, f := range files {
+		dst := filepath.Join(targetDir, b.dstPath(tpl.buildDir, f))
+		if err := os.MkdirAll(filepath.Dir(dst), tpl.dirMode()); err != nil {
+			return fmt.Errorf("mkdir %s failed: %w", filepath.Dir(dst), err)
+		}
+		if IsDir(f) {
+			err := CopyDirMode(f, dst, fileMode, dirMode)
+			if err != nil {
+				return fmt.Errorf("failed to copy result folder: %w", err)
+			}
+		} else {
+			err := CopyFileMode(f, dst, fileMode)
+			if err != nil {
+				return fmt.Errorf("failed to copy result file: %w", err)
+			}
+		}
+		b.artifacts[r.Name] = append(b.artifacts[r.Name], dst)
+	}
+	b.stats.add(r.Name, PhaseCopy, time.Since(start))
+	if buildErr != nil {
+		return fmt.Errorf("failed to build template %s: %w", template, buildErr)
 	}
 	return nil
 }
 
+// postProcess applies the PDFPostProcess functions on all pdf files
+func (b *Build) postProcess(files []string) error {
+	for _, f := range files {
+		if !strings.HasSuffix(strings.ToLower(f), ".pdf") {
+			continue
+		}
+		for _, process := range b.PDFPostProcess {
+			if err := process(f); err != nil {
+				return fmt.Errorf("failed to post process %s: %w", f, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PDFCommand returns a post processor for Build.PDFPostProcess, which executes the command with the given
+// arguments and the path of the pdf as last argument, e.g. PDFCommand("qpdf", "--linearize", "--replace-input").
+func PDFCommand(name string, args ...string) func(path string) error {
+	return func(path string) error {
+		cmd := exec.Command(name, append(append([]string(nil), args...), path)...)
+		cmd.Dir = filepath.Dir(path)
+		cmd.Env = os.Environ()
+		res, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("'%s' failed: %s: %w", name, string(res), err)
+		}
+		return nil
+	}
+}
+
+// dstPath returns the relative destination of a generated file, which keeps the relative structure of the build
+// folder, unless a PathMapper is defined.
+func (b *Build) dstPath(buildDir string, f string) string {
+	rel, err := filepath.Rel(buildDir, f)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(f)
+	}
+	if b.PathMapper != nil {
+		return filepath.FromSlash(b.PathMapper(filepath.ToSlash(rel)))
+	}
+	return rel
+}
+
+// isEmpty returns true for elements without children, like a document with an empty body
+func isEmpty(d Discriminator) bool {
+	c, ok := d.(container)
+	return ok && len(c.children()) == 0
+}
+
+// prepare executes the calculation passes on the model, before it is rendered
+func prepare(root Discriminator) {
+	var docs []*Document
+	switch t := root.(type) {
+	case *Workspace:
+		for _, r := range t.Resources {
+			if doc, ok := r.(*Document); ok {
+				docs = append(docs, doc)
+			}
+		}
+	case *Document:
+		docs = append(docs, t)
+	}
+	for _, doc := range docs {
+		doc.NumberChapters()
+		doc.NumberFloats()
+		MarkAbbreviations(doc)
+	}
+}
+
 // provideTemplate either clones a repository (or pulls from it) or just returns a local path
 func (b *Build) provideTemplate(urlOrDir string) (string, error) {
 	if isUrl(urlOrDir) {
 		tmp := sha256.Sum224([]byte(urlOrDir))
 		dstDir := filepath.Join(b.tmpDir, "template", hex.EncodeToString(tmp[:]))
 		if _, err := os.Stat(dstDir); err == nil {
-			err := b.exec(dstDir, "git", "pull")
+			err := b.git(dstDir, "pull")
 			if err != nil {
 				return "", err
 			}
 			return dstDir, nil
 		}
-		err := os.MkdirAll(dstDir, os.ModePerm)
-		if err != nil {
-			return "", fmt.Errorf("failed to create template clone folder %s: %w", dstDir, err)
-		}
-
-		err = b.exec(dstDir, "git", "clone", urlOrDir, ".")
-		if err != nil {
+		if err := b.clone(urlOrDir, dstDir); err != nil {
 			return "", err
 		}
 		return dstDir, nil
@@ -122,17 +586,54 @@ func (b *Build) provideTemplate(urlOrDir string) (string, error) {
 	if _, err := os.Stat(urlOrDir); err != nil {
 		return "", fmt.Errorf("cannot find template %s: %w", urlOrDir, err)
 	}
+	if !IsDir(urlOrDir) {
+		return "", fmt.Errorf("template path is not a directory: %s", urlOrDir)
+	}
 	return urlOrDir, nil
 }
 
-func (b *Build) exec(dir string, name string, args ...string) error {
-	str := "cd " + dir + " && " + name + " " + strings.Join(args, " ")
+// git executes a git command, as soon as one of the GitConcurrency slots is available.
+func (b *Build) git(dir string, args ...string) error {
+	return b.gitContext(context.Background(), dir, args...)
+}
+
+// gitContext is like git but kills the git process, when the context is done.
+func (b *Build) gitContext(ctx context.Context, dir string, args ...string) error {
+	var err error
+	b.withGitSlot(func() {
+		err = b.exec(ctx, dir, "git", args...)
+	})
+	return err
+}
+
+// withGitSlot blocks until a git slot is available and releases it after f returns.
+func (b *Build) withGitSlot(f func()) {
+	b.gitMutex.Lock()
+	if b.gitSlots == nil {
+		n := b.GitConcurrency
+		if n <= 0 {
+			n = defaultGitConcurrency
+		}
+		b.gitSlots = make(chan struct{}, n)
+	}
+	slots := b.gitSlots
+	b.gitMutex.Unlock()
+
+	slots <- struct{}{}
+	defer func() {
+		<-slots
+	}()
+	f()
+}
+
+func (b *Build) exec(ctx context.Context, dir string, name string, args ...string) error {
+	str := redact("cd "+dir+" && "+name+" "+strings.Join(args, " "), b.Secrets)
 	fmt.Println(str)
-	cmd := exec.Command("git", args...)
+	cmd := exec.CommandContext(ctx, name, args...)
 	cmd.Dir = dir
 	cmd.Env = os.Environ()
 	res, err := cmd.CombinedOutput()
-	fmt.Println(string(res))
+	fmt.Println(redact(string(res), b.Secrets))
 	if err != nil {
 		return fmt.Errorf("'%s' failed: %w", str, err)
 	}
@@ -149,4 +650,15 @@ type BuildRule struct {
 	Id       string // Id of the root to apply
 	Template string // Template, either a local directory or an http/https git repository
 	Name     string // Name of the target folder in the build directory. The entire template result just copied over.
+
+	// Targets are optional sub folders of the template, e.g. html and latex. Each one is applied like a separate
+	// template on the same model and the result is put into the according sub folder of Name.
+	Targets []string
+
+	// SkipAutobuild keeps the rendered sources and does not run latexmk or make, so no TeX installation is required.
+	SkipAutobuild bool
+
+	// DependsOn contains the names of the rules, which are applied before this rule. Their generated files are
+	// available to the template by the deps function, e.g. to include pre-rendered pdf files.
+	DependsOn []string
 }
@@ -0,0 +1,552 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeFiles creates the given files (relative path to content) within a new temporary directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		fname := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fname), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeWorkspace(t *testing.T, fname string, ws *Workspace) {
+	t.Helper()
+	b, err := Marshal(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fname, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"index.txt.tmpl": "{{.Title}}"})
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+	input := filepath.Join(inDir, "ws.json")
+	writeWorkspace(t, input, &Workspace{Title: "first"})
+
+	build, err := NewBuild(&Workspace{Title: "first"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.InputFile = input
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+	events := make(chan BuildEvent, 16)
+	build.OnEvent = func(e BuildEvent) {
+		if e.Kind == EventFinished || e.Kind == EventFailed {
+			events <- e
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- build.Watch(ctx)
+	}()
+
+	waitFor := func(title string) {
+		t.Helper()
+		select {
+		case e := <-events:
+			if e.Err != nil {
+				t.Fatal(e.Err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no build for '%s'", title)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(outDir, "site", "index.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != title {
+			t.Fatalf("expected '%s' but got '%s'", title, string(b))
+		}
+	}
+
+	waitFor("first")
+	writeWorkspace(t, input, &Workspace{Title: "second"})
+	waitFor("second")
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildStats(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"index.txt.tmpl": "{{.Title}}", "style.css": "body{}"})
+	build, err := NewBuild(&Workspace{Title: "stats"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+	var finished *BuildStats
+	build.OnEvent = func(e BuildEvent) {
+		if e.Kind == EventFinished {
+			finished = e.Stats
+		}
+	}
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := build.Stats()
+	if stats == nil || stats != finished {
+		t.Fatal("expected stats from the finished event")
+	}
+	if stats.Phases[PhaseRender] <= 0 || stats.Rules["site"][PhaseRender] <= 0 {
+		t.Fatalf("expected render time but got %v", stats.Phases)
+	}
+	if stats.Total < stats.Phases[PhaseRender] {
+		t.Fatalf("total %v is less than render %v", stats.Total, stats.Phases[PhaseRender])
+	}
+}
+
+func TestBuildTargets(t *testing.T) {
+	stubLatexmk(t, "touch main.pdf")
+	tplDir := writeFiles(t, map[string]string{
+		"html/index.html.gohtml": "<h1>{{.Title}}</h1>",
+		"latex/latexmkrc":        "",
+		"latex/main.tex.tmpl":    `\title{ {{.Title}} }`,
+	})
+	outDir := t.TempDir()
+	build, err := NewBuild(&Workspace{Title: "a & b"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "manual", Targets: []string{"html", "latex"}})
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(outDir, "manual", "html", "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "<h1>a &amp; b</h1>" {
+		t.Fatalf("unexpected html %s", string(b))
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "manual", "latex", "main.pdf")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "manual", "latex", "index.html")); err == nil {
+		t.Fatal("targets must not be mixed")
+	}
+}
+
+func TestBuildToMemory(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"index.html.gohtml": "<p>{{.Title}}</p>", "css/style.css": "body{}"})
+	outDir := filepath.Join(t.TempDir(), "untouched")
+	build, err := NewBuild(&Workspace{Title: "memory"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+	files, err := build.BuildToMemory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(files["site/index.html"]) != "<p>memory</p>" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Fatalf("output dir must not be created: %v", err)
+	}
+}
+
+func TestWatchFunc(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"index.html.gohtml": "<p>{{.Title}}</p>"})
+	outDir := filepath.Join(t.TempDir(), "untouched")
+	build, err := NewBuild(&Workspace{Title: "watched"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+
+	var files map[string][]byte
+	ctx, cancel := context.WithCancel(context.Background())
+	err = build.WatchFunc(ctx, func(ctx context.Context) error {
+		defer cancel()
+		var err error
+		files, err = build.BuildToMemory(ctx)
+		return err
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected cancellation but got %v", err)
+	}
+	if string(files["site/index.html"]) != "<p>watched</p>" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	if _, err := os.Stat(outDir); !os.IsNotExist(err) {
+		t.Fatalf("output dir must not be created: %v", err)
+	}
+}
+
+func TestGitConcurrency(t *testing.T) {
+	for _, limit := range []int{0, 2} {
+		build, err := NewBuild(&Workspace{}, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		build.GitConcurrency = limit
+		want := limit
+		if want == 0 {
+			want = defaultGitConcurrency
+		}
+
+		var mutex sync.Mutex
+		running, max := 0, 0
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				build.withGitSlot(func() {
+					mutex.Lock()
+					running++
+					if running > max {
+						max = running
+					}
+					mutex.Unlock()
+					time.Sleep(5 * time.Millisecond)
+					mutex.Lock()
+					running--
+					mutex.Unlock()
+				})
+			}()
+		}
+		wg.Wait()
+		if max > want || max == 0 {
+			t.Fatalf("expected at most %d concurrent git operations but got %d", want, max)
+		}
+	}
+}
+
+func TestBuildFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported")
+	}
+	tplDir := writeFiles(t, map[string]string{"index.txt.tmpl": "{{.Title}}", "css/style.css": "body{}"})
+	outDir := t.TempDir()
+	build, err := NewBuild(&Workspace{Title: "modes"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.FileMode = 0640
+	build.DirMode = 0750
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]os.FileMode{
+		"site":               0750 | os.ModeDir,
+		"site/index.txt":     0640,
+		"site/css":           0750 | os.ModeDir,
+		"site/css/style.css": 0640,
+	} {
+		info, err := os.Stat(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode() != want {
+			t.Fatalf("%s: expected %v but got %v", name, want, info.Mode())
+		}
+	}
+}
+
+func TestBuildDefaultFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported")
+	}
+	tplDir := writeFiles(t, map[string]string{"index.txt.tmpl": "{{.Title}}"})
+	outDir := t.TempDir()
+	build, err := NewBuild(&Workspace{}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(outDir, "site", "index.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != DefaultFileMode {
+		t.Fatalf("expected %v but got %v", DefaultFileMode, info.Mode())
+	}
+}
+
+func TestBuildPreservesStructure(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{
+		"index.html.gohtml": "{{.Title}}",
+		"css/style.css":     "body{}",
+		"js/app.js":         "app()",
+		"css/info.txt":      "css",
+		"js/info.txt":       "js",
+	})
+	for _, mapper := range []func(string) string{nil, func(src string) string { return "static/" + src }} {
+		build, err := NewBuild(&Workspace{Title: "structure"}, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		build.PathMapper = mapper
+		build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+		files, err := build.BuildToMemory(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefix := "site/"
+		if mapper != nil {
+			prefix += "static/"
+		}
+		want := map[string]string{"index.html": "structure", "css/style.css": "body{}", "js/app.js": "app()",
+			"css/info.txt": "css", "js/info.txt": "js"}
+		if len(files) != len(want) {
+			t.Fatalf("expected %d files but got %v", len(want), files)
+		}
+		for name, content := range want {
+			if string(files[prefix+name]) != content {
+				t.Fatalf("expected %s in %s but got %v", content, prefix+name, files)
+			}
+		}
+	}
+}
+
+func TestBuildNestedFiles(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"docs/sub/page.html.gohtml": "{{.Title}}", "docs/sub/img/a.png": "png"})
+	var mapped []string
+	build, err := NewBuild(&Workspace{Title: "nested"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.PathMapper = func(src string) string {
+		mapped = append(mapped, src)
+		return src
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "site"})
+	files, err := build.BuildToMemory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(files["site/docs/sub/page.html"]) != "nested" || string(files["site/docs/sub/img/a.png"]) != "png" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	sort.Strings(mapped)
+	if strings.Join(mapped, ",") != "docs/sub/img/a.png,docs/sub/page.html" {
+		t.Fatalf("expected the mapper to get each file but got %v", mapped)
+	}
+}
+
+func TestPDFPostProcess(t *testing.T) {
+	stubLatexmk(t, "echo pdf > main.pdf")
+	tplDir := writeFiles(t, map[string]string{"latexmkrc": "", "main.tex.tmpl": "{{.Title}}"})
+	outDir := t.TempDir()
+	build, err := NewBuild(&Workspace{Title: "post"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	build.PDFPostProcess = append(build.PDFPostProcess, func(path string) error {
+		paths = append(paths, path)
+		return nil
+	}, PDFCommand("sh", "-c", `echo processed > "$0"`))
+	build.AddRule(&BuildRule{Template: tplDir, Name: "book"})
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || filepath.Base(paths[0]) != "main.pdf" {
+		t.Fatalf("expected main.pdf but got %v", paths)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(outDir, "book", "main.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "processed\n" {
+		t.Fatalf("expected the processed pdf but got %q", string(b))
+	}
+}
+
+func TestBuildWorkspace(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"out.txt.tmpl": "{{typeOf .}}:{{.Title}}"})
+	ws := createModel(t)
+	outDir := t.TempDir()
+	if err := BuildWorkspace(ws, outDir, &BuildRule{Id: "1234", Template: tplDir, Name: "a"}, &BuildRule{Template: tplDir, Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a": "*wdydoc.Document:", "b": "*wdydoc.Workspace:my workspace"} {
+		b, err := ioutil.ReadFile(filepath.Join(outDir, name, "out.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Fatalf("expected %q but got %q", want, string(b))
+		}
+	}
+}
+
+func TestApplyRule(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"out.txt.tmpl": "{{.Title}}"})
+	ws := &Workspace{Title: "first"}
+	outDir := t.TempDir()
+	build, err := NewBuild(ws, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "a"})
+	build.AddRule(&BuildRule{Template: tplDir, Name: "b"})
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	ws.Title = "second"
+	if err := build.ApplyRule("b"); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"a": "first", "b": "second"} {
+		b, err := ioutil.ReadFile(filepath.Join(outDir, name, "out.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Fatalf("expected %s in %s but got %s", want, name, string(b))
+		}
+	}
+	if rules := build.Stats().Rules; len(rules) != 1 {
+		t.Fatalf("expected only rule b but got %v", rules)
+	}
+	if err := build.ApplyRule("missing"); err == nil {
+		t.Fatal("expected unknown rule to fail")
+	}
+}
+
+func TestAllowEmpty(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"out.txt.tmpl": "{{.Title}}"})
+	ws := &Workspace{}
+	ws.NewDocument().Id = "empty"
+	full := ws.NewDocument()
+	full.Id = "full"
+	full.Add(Text("content"))
+
+	for _, allow := range []bool{false, true} {
+		build, err := NewBuild(ws, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		build.AllowEmpty = allow
+		var warnings []string
+		build.OnEvent = func(e BuildEvent) {
+			if e.Kind == EventWarning {
+				warnings = append(warnings, e.Err.Error())
+			}
+		}
+		build.AddRule(&BuildRule{Id: "empty", Template: tplDir, Name: "empty"})
+		build.AddRule(&BuildRule{Id: "full", Template: tplDir, Name: "full"})
+		if _, err := build.BuildToMemory(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"'empty' has no content to render"}
+		if allow {
+			want = nil
+		}
+		if !reflect.DeepEqual(warnings, want) {
+			t.Fatalf("expected %v but got %v", want, warnings)
+		}
+	}
+}
+
+func TestRuleDependencies(t *testing.T) {
+	chapterTpl := writeFiles(t, map[string]string{"chapter.txt.tmpl": "{{.Title}}"})
+	masterTpl := writeFiles(t, map[string]string{"master.txt.tmpl": `{{range $name, $files := deps}}{{$name}}:{{len $files}}{{end}}`})
+	outDir := t.TempDir()
+	build, err := NewBuild(&Workspace{Title: "ws"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	build.OnEvent = func(e BuildEvent) {
+		if e.Kind == EventRuleDone {
+			order = append(order, e.Rule.Name)
+		}
+	}
+	build.AddRule(&BuildRule{Template: masterTpl, Name: "master", DependsOn: []string{"chapter"}})
+	build.AddRule(&BuildRule{Template: chapterTpl, Name: "chapter"})
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "chapter,master" {
+		t.Fatalf("expected the dependency first but got %v", order)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(outDir, "master", "master.txt")); string(b) != "chapter:1" {
+		t.Fatalf("expected the artifacts of the dependency but got %s", b)
+	}
+
+	order = nil
+	if err := build.ApplyRule("master"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "chapter,master" {
+		t.Fatalf("expected the dependency to be applied again but got %v", order)
+	}
+
+	build.AddRule(&BuildRule{Template: chapterTpl, Name: "chapter", DependsOn: []string{"master"}})
+	if err := build.Apply(); err == nil || !strings.Contains(err.Error(), "cyclic") {
+		t.Fatalf("expected a cycle but got %v", err)
+	}
+}
+
+func TestExecCommandName(t *testing.T) {
+	if _, err := exec.LookPath("touch"); err != nil {
+		t.Skip("touch is not available")
+	}
+	dir := t.TempDir()
+	b := &Build{}
+	if err := b.exec(context.Background(), dir, "touch", "marker"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "marker")); err != nil {
+		t.Fatalf("expected the named command to be executed: %v", err)
+	}
+}
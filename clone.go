@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cloneSizeInterval is the delay between two size checks of a running clone
+var cloneSizeInterval = 100 * time.Millisecond
+
+// clone checks out the repository into dstDir, respecting MaxCloneSize and CloneTimeout. A failed or aborted clone
+// is removed, so that the next build does not pull into a partial checkout.
+func (b *Build) clone(url string, dstDir string) error {
+	if err := os.MkdirAll(dstDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create template clone folder %s: %w", dstDir, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if b.CloneTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), b.CloneTimeout)
+	}
+	defer cancel()
+
+	exceeded := make(chan struct{})
+	done := make(chan struct{})
+	if b.MaxCloneSize > 0 {
+		go func() {
+			ticker := time.NewTicker(cloneSizeInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if dirSize(dstDir) > b.MaxCloneSize {
+						close(exceeded)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	err := b.gitContext(ctx, dstDir, "clone", url, ".")
+	close(done)
+	select {
+	case <-exceeded:
+		err = fmt.Errorf("clone of %s exceeds %d bytes", redact(url, b.Secrets), b.MaxCloneSize)
+	default:
+		if err == nil && b.MaxCloneSize > 0 && dirSize(dstDir) > b.MaxCloneSize {
+			err = fmt.Errorf("clone of %s exceeds %d bytes", redact(url, b.Secrets), b.MaxCloneSize)
+		}
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("clone of %s exceeds %v: %w", redact(url, b.Secrets), b.CloneTimeout, ctx.Err())
+		}
+	}
+
+	if err != nil {
+		if rmErr := os.RemoveAll(dstDir); rmErr != nil {
+			return fmt.Errorf("%v and failed to remove partial clone: %w", err, rmErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// dirSize returns the sum of all file sizes within the directory
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -1,21 +1,30 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"github.com/worldiety/wdydoc"
 	"os"
+	"os/signal"
 )
 
 func main() {
 	fmt.Printf("wdydoc version '%s'\n", wdydoc.BuildGitCommit)
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+
 	help := flag.Bool("help", false, "shows this help")
-	format := flag.String("format", "json", "the input format type for the file of 'in'")
+	format := flag.String("format", "json", "the input format type for the file of 'in', either json or yaml")
 	in := flag.String("in", "", "the input markup file, as defined by 'format'")
 	out := flag.String("out", "", "the folder to place the generated files")
 	id := flag.String("id", "", "the id of the subtree to use for generation")
 	template := flag.String("template", "", "the local folder or remote git repository containing the template")
 	name := flag.String("name", "", "the subfolder name in 'out', to place the generated output")
+	dump := flag.Bool("dump", false, "prints the parsed model tree of 'in' and exits")
+	watch := flag.Bool("watch", false, "rebuild whenever the input file or a local template changes")
 
 	flag.Parse()
 	if *help {
@@ -23,23 +32,26 @@ func main() {
 		return
 	}
 
-	if len(*in) == 0 || len(*template) == 0 {
+	if len(*in) == 0 || len(*template) == 0 && !*dump {
 		fmt.Printf("invalid parameters\nusage:\n\n")
 		flag.PrintDefaults()
 		os.Exit(-5)
 	}
 
-	if *format != "json" {
-		fmt.Printf("only json is currently supported\n")
-		os.Exit(-1)
-	}
-
-	w, err := wdydoc.UnmarshalFile(*in)
+	w, err := unmarshal(*format, *in)
 	if err != nil {
 		fmt.Printf("cannot parse markup of '%s': %v\n", *in, err)
 		os.Exit(-2)
 	}
 
+	if *dump {
+		if err := wdydoc.Dump(w, os.Stdout); err != nil {
+			fmt.Printf("cannot dump markup: %v\n", err)
+			os.Exit(-2)
+		}
+		return
+	}
+
 	build, err := wdydoc.NewBuild(w, *out)
 	if err != nil {
 		fmt.Printf("cannot create build: %v\n", err)
@@ -51,9 +63,46 @@ func main() {
 		Name:     *name,
 	})
 
+	if *watch {
+		build.InputFile = *in
+		build.OnEvent = func(e wdydoc.BuildEvent) {
+			if e.Kind == wdydoc.EventFailed {
+				fmt.Printf("cannot apply build transformation: %v\n", e.Err)
+			}
+			if e.Kind == wdydoc.EventFinished {
+				fmt.Printf("build finished, watching for changes\n")
+			}
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt)
+		go func() {
+			<-sig
+			cancel()
+		}()
+		err = build.Watch(ctx)
+		if err != nil && err != context.Canceled {
+			fmt.Printf("cannot watch: %v\n", err)
+			os.Exit(-6)
+		}
+		return
+	}
+
 	err = build.Apply()
 	if err != nil {
 		fmt.Printf("cannot apply build transformation: %v\n", err)
 		os.Exit(-4)
 	}
 }
+
+// unmarshal decodes the markup file in the given format, which is either json or yaml
+func unmarshal(format string, fname string) (*wdydoc.Workspace, error) {
+	switch format {
+	case "json":
+		return wdydoc.UnmarshalFile(fname)
+	case "yaml":
+		return wdydoc.UnmarshalYAMLFile(fname)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s', only json and yaml are supported", format)
+	}
+}
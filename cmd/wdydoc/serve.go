@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/worldiety/wdydoc"
+)
+
+// versionPath is polled by the served html pages to reload themselves after a rebuild
+const versionPath = "_wdydoc/version"
+
+const reloadScript = `<script>
+(function () {
+	var version = null;
+	setInterval(function () {
+		fetch("/` + versionPath + `").then(function (r) { return r.text(); }).then(function (v) {
+			if (version !== null && version !== v) { location.reload(); }
+			version = v;
+		});
+	}, 1000);
+})();
+</script>`
+
+// serve renders the workspace into memory and serves it using http. The input file and a local template are
+// watched and the served files are replaced after each successful rebuild.
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	format := flags.String("format", "json", "the input format type for the file of 'in', either json or yaml")
+	in := flags.String("in", "", "the input markup file, as defined by 'format'")
+	id := flags.String("id", "", "the id of the subtree to use for generation")
+	template := flags.String("template", "", "the local folder or remote git repository containing the template")
+	port := flags.Int("port", 8080, "the http port to serve the rendered files at")
+	_ = flags.Parse(args)
+
+	if len(*in) == 0 || len(*template) == 0 {
+		fmt.Printf("invalid parameters\nusage:\n\n")
+		flags.PrintDefaults()
+		os.Exit(-5)
+	}
+
+	w, err := unmarshal(*format, *in)
+	if err != nil {
+		fmt.Printf("cannot parse markup of '%s': %v\n", *in, err)
+		os.Exit(-2)
+	}
+
+	// the output folder is never written, because all files are rendered into memory
+	build, err := wdydoc.NewBuild(w, "")
+	if err != nil {
+		fmt.Printf("cannot create build: %v\n", err)
+		os.Exit(-3)
+	}
+	build.InputFile = *in
+	build.AddRule(&wdydoc.BuildRule{Id: *id, Template: *template})
+
+	s := &site{}
+	build.OnEvent = func(e wdydoc.BuildEvent) {
+		if e.Kind == wdydoc.EventFailed {
+			fmt.Printf("cannot apply build transformation: %v\n", e.Err)
+		}
+	}
+	rebuild := func(ctx context.Context) error {
+		files, err := build.BuildToMemory(ctx)
+		if err != nil {
+			return err
+		}
+		s.update(files)
+		fmt.Printf("build finished, serving %d files at http://localhost:%d\n", len(files), *port)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	server := &http.Server{Addr: ":" + strconv.Itoa(*port), Handler: s}
+	go func() {
+		err := build.WatchFunc(ctx, rebuild)
+		if err != nil && err != context.Canceled {
+			fmt.Printf("cannot watch: %v\n", err)
+		}
+		_ = server.Close()
+	}()
+
+	err = server.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		fmt.Printf("cannot serve: %v\n", err)
+		os.Exit(-6)
+	}
+}
+
+// A site serves the rendered files from memory
+type site struct {
+	mutex   sync.RWMutex
+	files   map[string][]byte
+	version int
+}
+
+func (s *site) update(files map[string][]byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.files = files
+	s.version++
+}
+
+func (s *site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == versionPath {
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = fmt.Fprint(w, s.version)
+		return
+	}
+	if _, ok := s.files[name]; !ok {
+		name = path.Join(name, "index.html")
+	}
+	b, ok := s.files[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(b)
+	}
+	if strings.HasPrefix(contentType, "text/html") {
+		b = injectReload(b)
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write(b)
+}
+
+// injectReload inserts the reload script before the closing body tag or appends it.
+func injectReload(page []byte) []byte {
+	idx := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+	if idx < 0 {
+		return append(append([]byte{}, page...), reloadScript...)
+	}
+	res := append([]byte{}, page[:idx]...)
+	res = append(res, reloadScript...)
+	return append(res, page[idx:]...)
+}
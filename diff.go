@@ -0,0 +1,107 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// The kinds of a DiffLine
+const (
+	DiffAdd     = "add"
+	DiffDel     = "del"
+	DiffContext = "context"
+)
+
+// A Diff shows the changes of some source code line by line, e.g. for a changelog.
+type Diff struct {
+	Hint  string // Hint is the language of the code, like Code.Hint
+	Lines []DiffLine
+}
+
+// A DiffLine is a single added, deleted or unchanged line of a Diff.
+type DiffLine struct {
+	Kind string // Kind is one of DiffAdd, DiffDel or DiffContext
+	Text string
+}
+
+// Prefix returns the unified diff prefix of the line, which is either +, - or a space.
+func (l DiffLine) Prefix() string {
+	switch l.Kind {
+	case DiffAdd:
+		return "+"
+	case DiffDel:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// NewDiff creates an empty diff for the given language hint
+func NewDiff(hint string) *Diff {
+	return &Diff{Hint: hint}
+}
+
+// AddLine appends an added line
+func (d *Diff) AddLine(text string) *Diff {
+	d.Lines = append(d.Lines, DiffLine{Kind: DiffAdd, Text: text})
+	return d
+}
+
+// DelLine appends a deleted line
+func (d *Diff) DelLine(text string) *Diff {
+	d.Lines = append(d.Lines, DiffLine{Kind: DiffDel, Text: text})
+	return d
+}
+
+// ContextLine appends an unchanged line
+func (d *Diff) ContextLine(text string) *Diff {
+	d.Lines = append(d.Lines, DiffLine{Kind: DiffContext, Text: text})
+	return d
+}
+
+// LineNumbers returns the 1-based numbers of all lines of the given kind, e.g. to highlight the added lines of a
+// Latex listing.
+func (d *Diff) LineNumbers(kind string) []int {
+	var res []int
+	for i, l := range d.Lines {
+		if l.Kind == kind {
+			res = append(res, i+1)
+		}
+	}
+	return res
+}
+
+func (d *Diff) Type() string {
+	return DiffType
+}
+
+func (d *Diff) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = d.Type()
+	m["hint"] = d.Hint
+	lines := make([]interface{}, 0, len(d.Lines))
+	for _, l := range d.Lines {
+		lines = append(lines, map[string]interface{}{"kind": l.Kind, "text": l.Text})
+	}
+	m["lines"] = lines
+	return m
+}
+
+func (d *Diff) fromJson(m map[string]interface{}) {
+	d.Hint = optString(m, "hint")
+	d.Lines = nil
+	for _, obj := range assertObjList(m["lines"]) {
+		d.Lines = append(d.Lines, DiffLine{Kind: optString(obj, "kind"), Text: optString(obj, "text")})
+	}
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRoundTrip(t *testing.T) {
+	diff := NewDiff("go").ContextLine("func main() {").DelLine("\tprintln(1)").AddLine("\tprintln(2)").ContextLine("}")
+	res := roundTrip(t, diff).(*Diff)
+	if !reflect.DeepEqual(diff, res) {
+		t.Fatalf("expected %+v but got %+v", diff, res)
+	}
+	if !reflect.DeepEqual(res.LineNumbers(DiffAdd), []int{3}) {
+		t.Fatal(res.LineNumbers(DiffAdd))
+	}
+
+	want := "<pre class=\"diff\"><code class=\"language-go\">" +
+		"<span class=\"diff-context\"> func main() {</span>\n" +
+		"<span class=\"diff-del\">-\tprintln(1)</span>\n" +
+		"<span class=\"diff-add\">+\tprintln(2)</span>\n" +
+		"<span class=\"diff-context\"> }</span>\n" +
+		"</code></pre>\n"
+	if got := renderHTML(res); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dump writes an indented and human readable tree of the element and its children. Each line shows the actual go
+// type, the type name, the most important fields and the amount of children. In contrast to the json
+// serialization, this reveals how the model has been reconstructed, e.g. which go type represents an element.
+func Dump(d Discriminator, w io.Writer) error {
+	var err error
+	var dump func(d Discriminator, depth int)
+	dump = func(d Discriminator, depth int) {
+		if err != nil {
+			return
+		}
+		var children []Discriminator
+		if c, ok := d.(container); ok {
+			children = c.children()
+		}
+		line := strings.Repeat("  ", depth) + typeOfName(d) + " " + d.Type()
+		if fields := dumpFields(d); fields != "" {
+			line += " " + fields
+		}
+		if len(children) > 0 {
+			line += fmt.Sprintf(" children=%d", len(children))
+		}
+		if _, err = fmt.Fprintln(w, line); err != nil {
+			return
+		}
+		for _, c := range children {
+			dump(c, depth+1)
+		}
+	}
+	dump(d, 0)
+	return err
+}
+
+// dumpFields returns the key fields of an element
+func dumpFields(d Discriminator) string {
+	switch t := d.(type) {
+	case *Workspace:
+		return fmt.Sprintf("title=%q version=%q format=%d", t.Title, t.Version, t.Format)
+	case *Document:
+		return fmt.Sprintf("id=%q title=%q authors=%d", t.Id, t.Title, len(t.Authors))
+	case *Part:
+		return fmt.Sprintf("title=%q", t.Title)
+	case *Chapter:
+		return fmt.Sprintf("title=%q level=%d", t.Title, t.Level)
+	case *Span:
+		return fmt.Sprintf("value=%q", t.Value)
+	case *VarRef:
+		return fmt.Sprintf("name=%q", t.Name)
+	case *Code:
+		return fmt.Sprintf("hint=%q caption=%q lines=%d", t.Hint, t.Caption, len(t.Lines))
+	case *Diff:
+		return fmt.Sprintf("hint=%q lines=%d", t.Hint, len(t.Lines))
+	case *Image:
+		return fmt.Sprintf("src=%q", t.Src)
+	case *RevisionHistory:
+		return fmt.Sprintf("entries=%d", len(t.Entries))
+	case *Table:
+		return fmt.Sprintf("caption=%q header=%d rows=%d", t.Caption, len(t.Header), len(t.Rows))
+	case *TableCell:
+		if t.Align != "" {
+			return fmt.Sprintf("align=%q", t.Align)
+		}
+	case *Abbreviation:
+		return fmt.Sprintf("short=%q long=%q", t.Short, t.Long)
+	case *Embed:
+		return fmt.Sprintf("url=%q", t.URL)
+	case *Hyperlink:
+		return fmt.Sprintf("href=%q", t.Href)
+	case *Tooltip:
+		return fmt.Sprintf("note=%q", t.Note)
+	case *Attachment:
+		return fmt.Sprintf("src=%q", t.Src)
+	case *List:
+		return fmt.Sprintf("ordered=%v", t.Ordered)
+	case *ListEntry:
+		if t.Checked != nil {
+			return fmt.Sprintf("checked=%v", *t.Checked)
+		}
+	}
+	return ""
+}
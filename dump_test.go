@@ -0,0 +1,44 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	sb := &strings.Builder{}
+	if err := Dump(createModel(t), sb); err != nil {
+		t.Fatal(err)
+	}
+	dump := sb.String()
+	for _, line := range []string{
+		"*wdydoc.Workspace workspace title=\"my workspace\" version=\"1.0.1\" format=1 children=1\n",
+		"\n  *wdydoc.Document document id=\"1234\"",
+		"\n    *wdydoc.Titlepage titlepage children=2\n",
+		"\n    *wdydoc.Chapter chapter title=\"my first chapter\" level=0 children=9\n",
+		"\n      *wdydoc.Chapter chapter title=\"a section\" level=1 children=2\n",
+		"\n        *wdydoc.Chapter chapter title=\"a subsection\" level=2 children=1\n",
+		"\n    *wdydoc.Chapter chapter title=\"another main chapter\" level=0 children=1\n",
+		"\n      wdydoc.defaultType newline\n",
+	} {
+		if !strings.Contains(dump, line) {
+			t.Fatalf("expected %q in dump:\n%s", line, dump)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// The known providers of an Embed
+const (
+	ProviderYouTube = "youtube"
+	ProviderVimeo   = "vimeo"
+	ProviderCodePen = "codepen"
+	ProviderTwitter = "twitter"
+)
+
+// oembedEndpoints are the oEmbed api urls by provider
+var oembedEndpoints = map[string]string{
+	ProviderYouTube: "https://www.youtube.com/oembed",
+	ProviderVimeo:   "https://vimeo.com/api/oembed.json",
+	ProviderCodePen: "https://codepen.io/api/oembed",
+	ProviderTwitter: "https://publish.twitter.com/oembed",
+}
+
+// oembedTimeout limits a single oEmbed request
+var oembedTimeout = 10 * time.Second
+
+// An Embed is external web content, like a video, a tweet or a pen. Html outputs embed it, usually as an iframe,
+// and all other outputs link to the URL.
+type Embed struct {
+	URL      string
+	Provider string // Provider is optional and detected from the URL, see DetectProvider
+}
+
+func (e *Embed) Type() string {
+	return EmbedType
+}
+
+func (e *Embed) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = e.Type()
+	m["url"] = e.URL
+	optSet(m, "provider", e.Provider)
+	return m
+}
+
+func (e *Embed) fromJson(m map[string]interface{}) {
+	e.URL = optString(m, "url")
+	e.Provider = optString(m, "provider")
+}
+
+// DetectProvider returns the Provider or guesses it from the host of the URL. Unknown hosts result in the empty
+// string.
+func (e *Embed) DetectProvider() string {
+	if e.Provider != "" {
+		return e.Provider
+	}
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return ""
+	}
+	switch strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.") {
+	case "youtube.com", "youtu.be", "m.youtube.com":
+		return ProviderYouTube
+	case "vimeo.com", "player.vimeo.com":
+		return ProviderVimeo
+	case "codepen.io":
+		return ProviderCodePen
+	case "twitter.com", "x.com":
+		return ProviderTwitter
+	default:
+		return ""
+	}
+}
+
+// EmbedHTML constructs the embedding html of a known provider without any network access. Unknown providers are
+// just linked.
+func (e *Embed) EmbedHTML() string {
+	if src := e.iframeSrc(); src != "" {
+		return fmt.Sprintf(`<iframe class="embed embed-%s" src="%s" width="560" height="315" frameborder="0" allowfullscreen></iframe>`,
+			e.DetectProvider(), html.EscapeString(src))
+	}
+	link := fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(e.URL), html.EscapeString(e.URL))
+	if e.DetectProvider() == ProviderTwitter {
+		return `<blockquote class="twitter-tweet">` + link + `</blockquote>`
+	}
+	return link
+}
+
+// iframeSrc returns the player url of the provider or the empty string
+func (e *Embed) iframeSrc() string {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	last := path.Base(u.Path)
+	switch e.DetectProvider() {
+	case ProviderYouTube:
+		id := u.Query().Get("v")
+		if id == "" && (strings.HasSuffix(u.Hostname(), "youtu.be") || len(segments) == 2 && segments[0] == "embed") {
+			id = last
+		}
+		if id == "" {
+			return ""
+		}
+		return "https://www.youtube-nocookie.com/embed/" + url.PathEscape(id)
+	case ProviderVimeo:
+		if last == "" || last == "/" || last == "." {
+			return ""
+		}
+		return "https://player.vimeo.com/video/" + url.PathEscape(last)
+	case ProviderCodePen:
+		if len(segments) != 3 || segments[1] != "pen" {
+			return ""
+		}
+		return "https://codepen.io/" + url.PathEscape(segments[0]) + "/embed/" + url.PathEscape(segments[2])
+	default:
+		return ""
+	}
+}
+
+// resolveOEmbed requests the embedding html from the oEmbed api of the provider
+func (e *Embed) resolveOEmbed() (string, error) {
+	endpoint, ok := oembedEndpoints[e.DetectProvider()]
+	if !ok {
+		return "", fmt.Errorf("no oEmbed endpoint for %s", e.URL)
+	}
+	client := &http.Client{Timeout: oembedTimeout}
+	resp, err := client.Get(endpoint + "?format=json&url=" + url.QueryEscape(e.URL))
+	if err != nil {
+		return "", fmt.Errorf("oEmbed request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oEmbed request for %s failed: %s", e.URL, resp.Status)
+	}
+	var res struct {
+		HTML string `json:"html"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("invalid oEmbed response: %w", err)
+	}
+	if res.HTML == "" {
+		return "", fmt.Errorf("oEmbed response for %s contains no html", e.URL)
+	}
+	return res.HTML, nil
+}
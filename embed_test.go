@@ -0,0 +1,82 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	e := &Embed{URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Provider: ProviderYouTube}
+	if got := roundTrip(t, e); !reflect.DeepEqual(e, got) {
+		t.Fatalf("expected %+v but got %+v", e, got)
+	}
+
+	for u, want := range map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ":  `src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"`,
+		"https://youtu.be/dQw4w9WgXcQ":                 `src="https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ"`,
+		"https://vimeo.com/76979871":                   `src="https://player.vimeo.com/video/76979871"`,
+		"https://codepen.io/someone/pen/abcDEF":        `src="https://codepen.io/someone/embed/abcDEF"`,
+		"https://twitter.com/golang/status/1234567890": `<blockquote class="twitter-tweet">`,
+		"https://example.com/page":                     `<a href="https://example.com/page">`,
+	} {
+		if got := (&Embed{URL: u}).EmbedHTML(); !strings.Contains(got, want) {
+			t.Fatalf("expected %s in %s", want, got)
+		}
+	}
+	if got := renderHTML(&Document{Body: []Discriminator{e}}); !strings.Contains(got, "<iframe") {
+		t.Fatal(got)
+	}
+	if got := renderLatex(e); !strings.HasPrefix(got, "\\href{https://www.youtube.com/watch?v=dQw4w9WgXcQ}") {
+		t.Fatal(got)
+	}
+}
+
+func TestOEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("url") != "https://vimeo.com/1" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"html": "<iframe src=\"resolved\"></iframe>"}`))
+	}))
+	defer srv.Close()
+	endpoint := oembedEndpoints[ProviderVimeo]
+	oembedEndpoints[ProviderVimeo] = srv.URL
+	defer func() {
+		oembedEndpoints[ProviderVimeo] = endpoint
+	}()
+
+	tpl := &Template{}
+	if got := tpl.oembed(&Embed{URL: "https://vimeo.com/1"}); got != (&Embed{URL: "https://vimeo.com/1"}).EmbedHTML() {
+		t.Fatalf("expected no network access by default but got %s", got)
+	}
+	tpl.ResolveEmbeds = true
+	if got := tpl.oembed(&Embed{URL: "https://vimeo.com/1"}); got != `<iframe src="resolved"></iframe>` {
+		t.Fatal(got)
+	}
+	if got := tpl.oembed(&Embed{URL: "https://vimeo.com/2"}); !strings.Contains(got, "player.vimeo.com/video/2") {
+		t.Fatalf("expected the fallback but got %s", got)
+	}
+	if len(tpl.warnings) != 1 || !strings.Contains(tpl.warnings[0].Error(), "https://vimeo.com/2") {
+		t.Fatalf("expected a warning but got %v", tpl.warnings)
+	}
+}
@@ -69,3 +69,34 @@ func is(i Discriminator, name string) bool {
 func strOf(i interface{}) string {
 	return fmt.Sprintf("%v", i)
 }
+
+// WrapURL returns a Latex hyperref link to the url, whose visible text may wrap after the characters / . - ? & and =,
+// so that long urls do not overflow the margin. The target of the link is not altered.
+func WrapURL(url string) string {
+	sb := &strings.Builder{}
+	sb.WriteString(`\href{`)
+	sb.WriteString(escapeHref(url))
+	sb.WriteString("}{")
+	for _, r := range url {
+		sb.WriteString(EscapeLatex(string(r)))
+		switch r {
+		case '/', '.', '-', '?', '&', '=':
+			sb.WriteString(`\allowbreak{}`)
+		}
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// escapeHref escapes the characters of an url, which are special even in the target argument of \href
+func escapeHref(url string) string {
+	sb := &strings.Builder{}
+	for _, r := range url {
+		switch r {
+		case '%', '#':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapURL(t *testing.T) {
+	url := "https://example.com/a-long/path_name?x=1#frag"
+	got := WrapURL(url)
+	want := `\href{https://example.com/a-long/path_name?x=1\#frag}{` +
+		`https:/\allowbreak{}/\allowbreak{}example.\allowbreak{}com/\allowbreak{}a-\allowbreak{}long/\allowbreak{}` +
+		`path\_name?\allowbreak{}x=\allowbreak{}1\#frag}`
+	if got != want {
+		t.Fatalf("expected %s but got %s", want, got)
+	}
+	target := got[len(`\href{`):strings.Index(got, "}{")]
+	if strings.ReplaceAll(target, `\#`, "#") != url {
+		t.Fatalf("the target must not be altered: %s", target)
+	}
+	if res := renderText(t, `{{wrapURL .}}`, "http://a.b"); res != `\href{http://a.b}{http:/\allowbreak{}/\allowbreak{}a.\allowbreak{}b}` {
+		t.Fatal(res)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// GoFormatter formats go source code like gofmt. It can be registered for the go hint in Build.CodeFormatters.
+func GoFormatter(source string) (string, error) {
+	b, err := format.Source([]byte(source))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\n"), nil
+}
+
+// GoValidator reports syntax errors of go code, which can be Build.CodeValidators for the go hint. Besides entire
+// files, it accepts snippets without a package clause and plain statements.
+func GoValidator(c *Code) error {
+	src := strings.Join(c.Lines, "\n")
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err == nil {
+		return nil
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+src, 0); err == nil {
+		return nil
+	}
+	_, err := parser.ParseFile(token.NewFileSet(), "", "package main\nfunc _() {\n"+src+"\n}", 0)
+	if list, ok := err.(scanner.ErrorList); ok {
+		// the lines of the statements are shifted by the wrapping package and function
+		for _, e := range list {
+			e.Pos.Line -= 2
+		}
+	}
+	return err
+}
+
+// FormatCode replaces the lines of each code block by the result of the formatter, which is registered for its
+// hint. A failing formatter keeps the lines and is reported with the location of the code block.
+func FormatCode(root Discriminator, formatters map[string]func(source string) (string, error)) []error {
+	if len(formatters) == 0 {
+		return nil
+	}
+	var res []error
+	walkPath(root, func(d Discriminator, path string) {
+		code, ok := d.(*Code)
+		if !ok {
+			return
+		}
+		formatter, ok := formatters[code.Hint]
+		if !ok {
+			return
+		}
+		formatted, err := formatter(strings.Join(code.Lines, "\n"))
+		if err != nil {
+			res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("cannot format %s code: %v", code.Hint, err)})
+			return
+		}
+		code.Lines = strings.Split(formatted, "\n")
+	})
+	return res
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCodeFormatters(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"out.txt.tmpl": "{{range .Body}}{{range .Lines}}{{.}};{{end}}{{end}}"})
+	ws := &Workspace{}
+	doc := ws.NewDocument()
+	doc.Id = "doc"
+	doc.Add(&Code{Hint: "upper", Lines: []string{"a", "b"}}, &Code{Hint: "other", Lines: []string{"c"}})
+	doc.Add(&Code{Hint: "broken", Lines: []string{"d"}})
+
+	build, err := NewBuild(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.CodeFormatters = map[string]func(string) (string, error){
+		"upper": func(source string) (string, error) {
+			return strings.ToUpper(source), nil
+		},
+		"broken": func(string) (string, error) {
+			return "", errors.New("syntax error")
+		},
+	}
+	build.AddRule(&BuildRule{Id: "doc", Template: tplDir, Name: "out"})
+	files, err := build.BuildToMemory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(files["out/out.txt"]); got != "A;B;c;d;" {
+		t.Fatal(got)
+	}
+
+	build.StrictCodeFormat = true
+	if _, err := build.BuildToMemory(context.Background()); err == nil || !strings.Contains(err.Error(), "doc: cannot format broken code: syntax error") {
+		t.Fatalf("expected the location of the broken code but got %v", err)
+	}
+}
+
+func TestGoFormatter(t *testing.T) {
+	code := &Code{Hint: "go", Lines: []string{"func main(){", "fmt.Println( 1 )", "}"}}
+	if errs := FormatCode(code, map[string]func(string) (string, error){"go": GoFormatter}); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if want := []string{"func main() {", "\tfmt.Println(1)", "}"}; !reflect.DeepEqual(code.Lines, want) {
+		t.Fatalf("expected %q but got %q", want, code.Lines)
+	}
+}
+
+func TestCodeValidators(t *testing.T) {
+	doc := &Document{Id: "doc"}
+	doc.NewChapter("examples").Add(
+		&Code{Hint: "go", Lines: []string{"package main", "", "func main() {}"}},
+		&Code{Hint: "go", Lines: []string{"x := 1", "fmt.Println(x"}},
+		&Code{Hint: "go", Lines: []string{"func add(a, b int) int {", "\treturn a + b", "}"}},
+		&Code{Hint: "sh", Lines: []string{"echo ("}},
+	)
+	if len(doc.CodeBlocks()) != 4 {
+		t.Fatalf("expected 4 code blocks but got %d", len(doc.CodeBlocks()))
+	}
+	errs := CheckCode(doc, map[string]func(*Code) error{"go": GoValidator})
+	if len(errs) != 1 {
+		t.Fatalf("expected a single invalid block but got %v", errs)
+	}
+	if msg := errs[0].Error(); !strings.HasPrefix(msg, "doc/examples: invalid go code: 2:") {
+		t.Fatal(msg)
+	}
+}
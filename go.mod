@@ -1,5 +1,13 @@
 module github.com/worldiety/wdydoc
 
-go 1.14
+go 1.18
 
-require github.com/worldiety/template-go v0.0.0-20200317134027-2dbb3f876673 // indirect
+require (
+	github.com/fsnotify/fsnotify v1.4.9
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/worldiety/template-go v0.0.0-20200317134027-2dbb3f876673 // indirect
+	golang.org/x/sys v0.0.0-20191005200804-aed5e4c7ecf9 // indirect
+)
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ContentHash returns the hex encoded SHA-256 digest of the persisted content of the document. Structurally equal
+// documents have the same hash, no matter how they have been constructed, because the json encoding sorts all
+// keys. Calculated fields, like chapter numbers, are not part of the hash.
+func (c *Document) ContentHash() string {
+	b, err := json.Marshal(c.toJson())
+	if err != nil {
+		// the model only consists of json compatible values
+		panic(err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import "testing"
+
+func TestContentHash(t *testing.T) {
+	a := &Document{Id: "doc", Title: "book"}
+	a.NewChapter("intro").Add(Text("hello"), Bold(Text("world")))
+	a.Add(&Code{Hint: "go", Lines: []string{"x := 1"}, Callouts: map[int]string{1: "one", 2: "two"}})
+
+	b := &Document{Title: "book"}
+	b.Id = "doc"
+	intro := &Chapter{Title: "intro"}
+	b.Add(intro, &Code{Callouts: map[int]string{2: "two", 1: "one"}, Lines: []string{"x := 1"}, Hint: "go"})
+	intro.Add(Text("hello"))
+	intro.Add(Bold(Text("world")))
+	b.NumberChapters()
+
+	if a.ContentHash() != b.ContentHash() {
+		t.Fatal("expected equal documents to have the same hash")
+	}
+	if len(a.ContentHash()) != 64 {
+		t.Fatalf("expected a sha256 hex digest but got %s", a.ContentHash())
+	}
+	intro.Add(Text("!"))
+	if a.ContentHash() == b.ContentHash() {
+		t.Fatal("expected a mutation to change the hash")
+	}
+}
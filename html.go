@@ -0,0 +1,471 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// RenderHTML is the built-in renderer, which writes the element and all of its children as an html fragment,
+// without any document scaffold or styles. Consecutive inline elements are wrapped into paragraphs, which are
+// ended by a ParagraphBreak or any block element, like a Chapter or Code.
+func RenderHTML(d Discriminator, out io.Writer) error {
+	r := &htmlRenderer{}
+	r.render(d)
+	if _, err := io.WriteString(out, r.sb.String()); err != nil {
+		return fmt.Errorf("unable to write html: %w", err)
+	}
+	return nil
+}
+
+// renderHTML is the template function variant of RenderHTML
+func renderHTML(d Discriminator) string {
+	r := &htmlRenderer{}
+	r.render(d)
+	return r.sb.String()
+}
+
+// renderHTML is the template function variant of RenderHTML, which respects the HTMLCodeCopyButton option
+func (p *Template) renderHTML(d Discriminator) string {
+	r := &htmlRenderer{copyButton: p.HTMLCodeCopyButton, sourceMap: p.EmitSourceMap}
+	r.render(d)
+	p.recordFragment(r.sb.String(), r.spans)
+	return r.sb.String()
+}
+
+// codeBlock is the template function, which renders the code including its copy button widget
+func codeBlock(c *Code) string {
+	r := &htmlRenderer{copyButton: true}
+	r.renderCode(c)
+	return r.sb.String()
+}
+
+type htmlRenderer struct {
+	sb         strings.Builder
+	doc        *Document // doc is the current document, e.g. to render its table of contents
+	copyButton bool      // copyButton adds a button to each code block, which copies its lines
+	sourceMap  bool      // sourceMap records the spans of all block elements
+	spans      []sourceSpan
+}
+
+func (r *htmlRenderer) printf(format string, args ...interface{}) {
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+}
+
+// render writes a single element
+func (r *htmlRenderer) render(d Discriminator) {
+	if r.sourceMap && isBlock(d) {
+		start := r.sb.Len()
+		defer func() {
+			r.spans = append(r.spans, sourceSpan{d: d, start: start, end: r.sb.Len()})
+		}()
+	}
+	switch t := d.(type) {
+	case *Workspace:
+		for _, res := range t.Resources {
+			r.render(res)
+		}
+	case *Document:
+		parent := r.doc
+		r.doc = t
+		r.printf("<article id=\"%s\">\n", html.EscapeString(t.Id))
+		if t.Title != "" {
+			r.printf("<h1>%s</h1>\n", html.EscapeString(t.Title))
+		}
+		for _, a := range t.Authors {
+			r.renderAuthor(a)
+		}
+		r.renderBlocks(t.Body)
+		r.printf("</article>\n")
+		r.doc = parent
+	case *Part:
+		r.printf("<section class=\"part\">\n<h1 class=\"part-title\">")
+		if t.Number != "" {
+			r.printf("Part %s: ", html.EscapeString(t.Number))
+		}
+		r.printf("%s</h1>\n", html.EscapeString(t.Title))
+		r.renderBlocks(t.Body)
+		r.printf("</section>\n")
+	case *Chapter:
+		h := t.Level + 2
+		if h > 6 {
+			h = 6
+		}
+		if t.IsLandscape() {
+			r.printf("<section class=\"landscape\">\n<h%d>", h)
+		} else {
+			r.printf("<section>\n<h%d>", h)
+		}
+		if t.Number != "" {
+			r.printf("%s ", html.EscapeString(t.Number))
+		}
+		r.printf("%s</h%d>\n", html.EscapeString(t.Title), h)
+		r.renderBlocks(t.Body)
+		r.printf("</section>\n")
+	case *Span:
+		r.printf("%s", html.EscapeString(t.Value))
+	case *VarRef:
+		r.printf("%s", html.EscapeString(t.Value))
+	case *Code:
+		r.renderCode(t)
+	case *Diff:
+		r.printf("<pre class=\"diff\"><code")
+		if t.Hint != "" {
+			r.printf(" class=\"language-%s\"", html.EscapeString(t.Hint))
+		}
+		r.printf(">")
+		for _, l := range t.Lines {
+			r.printf("<span class=\"diff-%s\">%s%s</span>\n", html.EscapeString(l.Kind), l.Prefix(), html.EscapeString(l.Text))
+		}
+		r.printf("</code></pre>\n")
+	case *Image:
+		if !t.Inline {
+			r.printf("<figure>")
+		}
+		r.printf("<img src=\"%s\"", html.EscapeString(t.Src))
+		if t.Width != "" {
+			r.printf(" width=\"%s\"", html.EscapeString(t.Width))
+		}
+		if t.Height != "" {
+			r.printf(" height=\"%s\"", html.EscapeString(t.Height))
+		}
+		r.printf(">")
+		if t.Inline {
+			return
+		}
+		if t.Number > 0 {
+			r.printf("<figcaption>Figure %d</figcaption>", t.Number)
+		}
+		r.printf("</figure>\n")
+	case *List:
+		r.renderList(t)
+	case *ListEntry:
+		r.printf("<li>")
+		if t.IsTask() {
+			checked := ""
+			if t.IsChecked() {
+				checked = " checked"
+			}
+			r.printf("<input type=\"checkbox\" disabled%s> ", checked)
+		}
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		r.printf("</li>\n")
+	case *Align:
+		if t.Alignment == "" {
+			r.renderBlocks(t.Body)
+			return
+		}
+		r.printf("<div style=\"text-align: %s\">\n", html.EscapeString(t.Alignment))
+		r.renderBlocks(t.Body)
+		r.printf("</div>\n")
+	case *Titlepage:
+		r.printf("<header class=\"titlepage\">\n")
+		if t.Cover != nil {
+			r.printf("<img class=\"cover\" src=\"%s\">\n", html.EscapeString(t.Cover.Src))
+		}
+		r.renderBlocks(t.Body)
+		r.printf("</header>\n")
+	case *RevisionHistory:
+		r.printf("<table class=\"revisions\" style=\"page-break-before: always\">\n")
+		r.printf("<tr><th>Version</th><th>Date</th><th>Author</th><th>Changes</th></tr>\n")
+		for _, rev := range t.Entries {
+			r.printf("<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n", html.EscapeString(rev.Version),
+				html.EscapeString(rev.Date), html.EscapeString(rev.Author), html.EscapeString(rev.Summary))
+		}
+		r.printf("</table>\n")
+	case *Table:
+		r.renderTable(t)
+	case *Abbreviation:
+		r.printf("<abbr title=\"%s\">%s</abbr>", html.EscapeString(t.Long), html.EscapeString(t.Short))
+		if t.First && t.Long != "" {
+			r.printf(" (%s)", html.EscapeString(t.Long))
+		}
+	case *Embed:
+		r.printf("%s\n", t.EmbedHTML())
+	case *Attachment:
+		r.printf("<a class=\"attachment\" href=\"%s\" download", html.EscapeString(t.OutputPath()))
+		if t.MimeType != "" {
+			r.printf(" type=\"%s\"", html.EscapeString(t.MimeType))
+		}
+		r.printf(">%s</a>", html.EscapeString(t.DisplayLabel()))
+	case *Hyperlink:
+		href := safeHref(t.Href)
+		if href != "" {
+			r.printf("<a href=\"%s\">", html.EscapeString(href))
+		}
+		if len(t.Body) == 0 {
+			r.printf("%s", html.EscapeString(t.Href))
+		}
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		if href != "" {
+			r.printf("</a>")
+		}
+	case *Tooltip:
+		r.printf("<span class=\"tooltip\" title=\"%s\">", html.EscapeString(t.Note))
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		r.printf("</span>")
+	case *MarginNote:
+		r.printf("<span class=\"marginnote\">")
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		r.printf("</span>")
+	case *defaultBody:
+		r.renderGroup(t)
+	default:
+		switch d.Type() {
+		case LineBreakType:
+			r.printf("<br>")
+		case ParagraphBreakType:
+			r.printf("<p></p>\n")
+		case NewpageType:
+			// pages are meaningless in html
+		case TOCType:
+			r.renderTOC()
+		default:
+			if c, ok := d.(container); ok {
+				for _, e := range c.children() {
+					r.render(e)
+				}
+			}
+		}
+	}
+}
+
+// renderBlocks writes the body of a document or chapter and wraps consecutive inline elements into paragraphs
+func (r *htmlRenderer) renderBlocks(body []Discriminator) {
+	open := false
+	closeParagraph := func() {
+		if open {
+			r.printf("</p>\n")
+			open = false
+		}
+	}
+	for _, e := range body {
+		switch {
+		case is(e, ParagraphBreakType):
+			closeParagraph()
+		case isBlock(e):
+			closeParagraph()
+			r.render(e)
+		default:
+			if !open {
+				r.printf("<p>")
+				open = true
+			}
+			r.render(e)
+		}
+	}
+	closeParagraph()
+}
+
+// renderAuthor writes the avatar image of the author or a circle with the initials as fallback
+func (r *htmlRenderer) renderAuthor(a *Author) {
+	name := html.EscapeString(strings.TrimSpace(a.Firstname + " " + a.Lastname))
+	r.printf("<address class=\"author\">")
+	if a.AvatarURL != "" {
+		r.printf("<img class=\"avatar\" src=\"%s\" alt=\"%s\">", html.EscapeString(a.AvatarURL), name)
+	} else {
+		r.printf("<span class=\"avatar initials\" style=\"display: inline-block; border-radius: 50%%; "+
+			"width: 2em; height: 2em; line-height: 2em; text-align: center\">%s</span>", html.EscapeString(a.Initials()))
+	}
+	r.printf(" %s</address>\n", name)
+}
+
+// renderCode writes a code block, which is wrapped into a figure with a numbered caption, if it is a listing
+func (r *htmlRenderer) renderCode(c *Code) {
+	if c.Caption != "" {
+		r.printf("<figure class=\"listing\"")
+		if c.Id != "" {
+			r.printf(" id=\"%s\"", html.EscapeString(c.Id))
+		}
+		r.printf(">\n")
+	}
+	if r.copyButton {
+		r.printf("<div class=\"code-block\" style=\"position: relative\"><button class=\"copy-button\" type=\"button\" "+
+			"style=\"position: absolute; top: 0.5em; right: 0.5em\" data-code=\"%s\" "+
+			"onclick=\"navigator.clipboard.writeText(this.dataset.code)\">Copy</button>", html.EscapeString(strings.Join(c.Lines, "\n")))
+	}
+	r.printf("<pre><code")
+	if c.Hint != "" {
+		r.printf(" class=\"language-%s\"", html.EscapeString(c.Hint))
+	}
+	r.printf(">")
+	markers := c.calloutMarkers()
+	for i, line := range c.VisibleLines() {
+		if i > 0 {
+			r.printf("\n")
+		}
+		r.printf("%s", html.EscapeString(line))
+		if n, ok := markers[i+1]; ok {
+			r.printf(" <span class=\"callout\">%s</span>", calloutMarker(n))
+		}
+	}
+	r.printf("</code></pre>")
+	if r.copyButton {
+		r.printf("</div>")
+	}
+	r.printf("\n")
+	if len(c.CalloutLines()) > 0 {
+		r.printf("<dl class=\"callouts\">\n")
+		for i, line := range c.CalloutLines() {
+			r.printf("<dt>%s</dt><dd>%s</dd>\n", calloutMarker(i+1), html.EscapeString(c.Callouts[line]))
+		}
+		r.printf("</dl>\n")
+	}
+	if c.Caption != "" {
+		r.printf("<figcaption>")
+		if c.Number > 0 {
+			r.printf("Listing %d: ", c.Number)
+		}
+		r.printf("%s</figcaption>\n</figure>\n", html.EscapeString(c.Caption))
+	}
+}
+
+func (r *htmlRenderer) renderTable(t *Table) {
+	r.printf("<table>\n")
+	if t.Caption != "" {
+		r.printf("<caption>")
+		if t.Number > 0 {
+			r.printf("Table %d: ", t.Number)
+		}
+		r.printf("%s</caption>\n", html.EscapeString(t.Caption))
+	}
+	if len(t.Header) > 0 {
+		r.printf("<thead>\n")
+		r.renderTableRows(t.Header, "th")
+		r.printf("</thead>\n")
+	}
+	r.printf("<tbody>\n")
+	r.renderTableRows(t.Rows, "td")
+	r.printf("</tbody>\n</table>\n")
+}
+
+func (r *htmlRenderer) renderTableRows(rows []*TableRow, tag string) {
+	for _, row := range rows {
+		r.printf("<tr>")
+		for _, cell := range row.Cells {
+			r.printf("<%s", tag)
+			if cell.Align != "" {
+				r.printf(" style=\"text-align: %s\"", html.EscapeString(cell.Align))
+			}
+			r.printf(">")
+			for _, e := range cell.Body {
+				r.render(e)
+			}
+			r.printf("</%s>", tag)
+		}
+		r.printf("</tr>\n")
+	}
+}
+
+// isBlock returns true for elements which cannot be part of a paragraph
+func isBlock(d Discriminator) bool {
+	if img, ok := d.(*Image); ok && img.Inline {
+		return false
+	}
+	switch d.Type() {
+	case DocumentType, PartType, ChapterType, CodeType, DiffType, AlignType, EmbedType, RevisionHistoryType, TableType, ImageType, ListType, TitlepageType, TOCType, NewpageType, AppendixType:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *htmlRenderer) renderGroup(g *defaultBody) {
+	tag := ""
+	switch g.Type() {
+	case BoldType:
+		tag = "strong"
+	case ItalicType:
+		tag = "em"
+	case UnderlineType:
+		tag = "u"
+	}
+	if tag != "" {
+		r.printf("<%s>", tag)
+	}
+	for _, e := range g.Body {
+		r.render(e)
+	}
+	if tag != "" {
+		r.printf("</%s>", tag)
+	}
+}
+
+func (r *htmlRenderer) renderList(l *List) {
+	tag := "ul"
+	if l.Ordered {
+		tag = "ol"
+	}
+	r.printf("<%s", tag)
+	if l.Ordered && l.Start != 0 {
+		r.printf(" start=\"%s\"", strconv.Itoa(l.Start))
+	}
+	if l.Marker != "" {
+		r.printf(" style=\"list-style-type: %s\"", html.EscapeString(l.Marker))
+	}
+	r.printf(">\n")
+	for _, item := range l.Items {
+		if _, ok := item.(*ListEntry); ok {
+			r.render(item)
+			continue
+		}
+		r.printf("<li>")
+		r.render(item)
+		r.printf("</li>\n")
+	}
+	r.printf("</%s>\n", tag)
+}
+
+// renderTOC writes the table of contents of the current document as nested lists
+func (r *htmlRenderer) renderTOC() {
+	if r.doc == nil {
+		return
+	}
+	r.printf("<nav class=\"toc\">\n")
+	depth := -1
+	for _, e := range r.doc.TableOfContents() {
+		for depth < e.Level {
+			r.printf("<ul>\n")
+			depth++
+		}
+		for depth > e.Level {
+			r.printf("</ul>\n")
+			depth--
+		}
+		r.printf("<li>")
+		if e.Number != "" {
+			r.printf("%s ", html.EscapeString(e.Number))
+		}
+		r.printf("%s</li>\n", html.EscapeString(e.Title))
+	}
+	for ; depth >= 0; depth-- {
+		r.printf("</ul>\n")
+	}
+	r.printf("</nav>\n")
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBreaks(t *testing.T) {
+	chap := &Chapter{Title: "breaks"}
+	chap.Add(Text("a"), LineBreak(), Text("b"), ParagraphBreak(), Text("c"), Newline())
+
+	res := roundTrip(t, chap).(*Chapter)
+	var types []string
+	for _, e := range res.Body {
+		types = append(types, e.Type())
+	}
+	want := []string{TextType, LineBreakType, TextType, ParagraphBreakType, TextType, LineBreakType}
+	if len(types) != len(want) {
+		t.Fatalf("expected %v but got %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("expected %v but got %v", want, types)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := RenderHTML(res, buf); err != nil {
+		t.Fatal(err)
+	}
+	wantHTML := "<section>\n<h2>breaks</h2>\n<p>a<br>b</p>\n<p>c<br></p>\n</section>\n"
+	if buf.String() != wantHTML {
+		t.Fatalf("expected %q but got %q", wantHTML, buf.String())
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	doc := &Document{Id: "doc", Title: "A & B"}
+	doc.Add(TOC())
+	doc.NewChapter("intro").Add(Bold(Text("<x>")), &Code{Hint: "go", Lines: []string{"a", "b"}}, Text("tail"))
+	doc.Add(OrderedList(ListItem(Text("one")), Task(true, Text("two"))))
+	doc.NumberChapters()
+
+	got := renderHTML(doc)
+	want := "<article id=\"doc\">\n<h1>A &amp; B</h1>\n" +
+		"<nav class=\"toc\">\n<ul>\n<li>1 intro</li>\n</ul>\n</nav>\n" +
+		"<section>\n<h2>1 intro</h2>\n<p><strong>&lt;x&gt;</strong></p>\n" +
+		"<pre><code class=\"language-go\">a\nb</code></pre>\n<p>tail</p>\n</section>\n" +
+		"<ol>\n<li>one</li>\n<li><input type=\"checkbox\" disabled checked> two</li>\n</ol>\n" +
+		"</article>\n"
+	if got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+}
+
+func TestHTMLCodeCopyButton(t *testing.T) {
+	code := &Code{Hint: "go", Lines: []string{`fmt.Println("<b>")`, "x := 1"}}
+	button := `data-code="fmt.Println(&#34;&lt;b&gt;&#34;)` + "\n" + `x := 1" onclick="navigator.clipboard.writeText(this.dataset.code)">Copy</button>`
+	if got := renderHTML(code); strings.Contains(got, "copy-button") {
+		t.Fatalf("expected no button by default but got %s", got)
+	}
+	if got := codeBlock(code); !strings.Contains(got, button) || !strings.HasSuffix(got, "</code></pre></div>\n") {
+		t.Fatal(got)
+	}
+
+	tplDir := writeFiles(t, map[string]string{"index.txt.tmpl": "{{renderHTML .}}"})
+	tpl, err := ReadTemplate(tplDir, filepath.Join(t.TempDir(), "build"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.HTMLCodeCopyButton = true
+	tpl.NoAutobuild = true
+	files, err := tpl.Build(&Document{Body: []Discriminator{code}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), button) {
+		t.Fatalf("expected the copy button in %s", b)
+	}
+}
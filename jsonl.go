@@ -0,0 +1,71 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A jsonlRecord is a single line written by WriteJSONL
+type jsonlRecord struct {
+	Type  string `json:"type"`
+	Depth int    `json:"depth"`
+	Text  string `json:"text"`
+	Path  string `json:"path"`
+}
+
+// WriteJSONL writes one flat json object per line for the workspace and each element, in document order. Each
+// record contains the type name, the nesting depth, the own text of the element (without the text of its
+// children) and its location, like 1234/my first chapter.
+func (w *Workspace) WriteJSONL(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	var err error
+	walkPathDepth(w, func(d Discriminator, path string, depth int) {
+		if err != nil {
+			return
+		}
+		if encErr := enc.Encode(jsonlRecord{Type: d.Type(), Depth: depth, Text: ownText(d), Path: path}); encErr != nil {
+			err = fmt.Errorf("unable to write json line: %w", encErr)
+		}
+	})
+	return err
+}
+
+// ownText returns the text which belongs to the element itself, e.g. the title of a chapter
+func ownText(d Discriminator) string {
+	switch t := d.(type) {
+	case *Workspace:
+		return t.Title
+	case *Document:
+		return t.Title
+	case *Chapter:
+		return t.Title
+	case *Span:
+		return t.Value
+	case *VarRef:
+		return t.Value
+	case *Code, *Diff:
+		return strings.TrimSuffix(PlainText(t), "\n")
+	case *Image:
+		return t.Src
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONL(t *testing.T) {
+	ws := createModel(t)
+	sb := &strings.Builder{}
+	if err := ws.WriteJSONL(sb); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	Walk(ws, func(d Discriminator) bool {
+		count++
+		return true
+	})
+	lines := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(lines) != count {
+		t.Fatalf("expected %d lines but got %d", count, len(lines))
+	}
+	for _, line := range lines {
+		var rec jsonlRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid json line %q: %v", line, err)
+		}
+		if rec.Type == "" {
+			t.Fatalf("expected a type in %q", line)
+		}
+	}
+	if want := `{"type":"text","depth":3,"text":"my technical book","path":"1234"}`; lines[3] != want {
+		t.Fatalf("expected %s but got %s", want, lines[3])
+	}
+}
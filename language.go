@@ -0,0 +1,88 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are frequent words by ISO 639-1 language code, which rarely occur in other languages
+var stopWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "with", "this", "that", "which", "for", "not", "you", "be", "it", "have", "from", "was"},
+	"de": {"der", "die", "das", "und", "ist", "sind", "nicht", "mit", "ein", "eine", "zu", "von", "für", "auf", "sich", "dem", "den", "wird"},
+	"fr": {"le", "la", "les", "et", "est", "sont", "des", "une", "pas", "pour", "avec", "dans", "que", "qui", "sur", "du", "au", "ce"},
+	"es": {"el", "los", "las", "y", "es", "son", "del", "una", "por", "para", "con", "que", "no", "se", "lo", "como", "pero", "su"},
+	"it": {"il", "gli", "e", "è", "sono", "della", "di", "una", "per", "con", "che", "non", "si", "del", "come", "anche", "nel", "questo"},
+	"nl": {"de", "het", "een", "en", "is", "zijn", "niet", "met", "van", "voor", "op", "dat", "die", "ook", "wordt", "aan", "bij", "naar"},
+}
+
+const (
+	languageSampleWords = 2000 // languageSampleWords limits the amount of words to inspect
+	languageMinHits     = 5    // languageMinHits is the minimum amount of stop words of the detected language
+)
+
+// EffectiveLanguage returns the Language of the document or, if it is empty, the guess of DetectLanguage. The
+// document itself is not modified, so a build does not change the persisted markup.
+func (c *Document) EffectiveLanguage() string {
+	if c.Language != "" {
+		return c.Language
+	}
+	return c.DetectLanguage()
+}
+
+// DetectLanguage guesses the ISO 639-1 code of the language of the text, like en or de, by counting common stop
+// words of a handful of languages. It is conservative and returns the empty string, if there is not enough text
+// or if no language clearly dominates. Code is not inspected.
+func (c *Document) DetectLanguage() string {
+	sb := &strings.Builder{}
+	writePlainText(sb, c, false)
+	words := strings.FieldsFunc(strings.ToLower(sb.String()), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) > languageSampleWords {
+		words = words[:languageSampleWords]
+	}
+
+	hits := make(map[string]int)
+	for lang, stops := range stopWords {
+		set := make(map[string]bool, len(stops))
+		for _, w := range stops {
+			set[w] = true
+		}
+		for _, w := range words {
+			if set[w] {
+				hits[lang]++
+			}
+		}
+	}
+
+	best, bestHits, secondHits := "", 0, 0
+	for lang, n := range hits {
+		switch {
+		case n > bestHits:
+			best, bestHits, secondHits = lang, n, bestHits
+		case n > secondHits:
+			secondHits = n
+		}
+	}
+	// the best language needs enough evidence and must have at least twice the hits of any other language
+	if bestHits < languageMinHits || bestHits < 2*secondHits || bestHits*10 < len(words) {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	english := &Document{}
+	english.NewChapter("intro").Add(Text("The inventory system consists of a login server and a web application. "+
+		"It is used by the staff to manage the stock, which is stored in the warehouse."),
+		Bold(Text("This is not a draft")), &Code{Lines: []string{"der die das und ist"}})
+	if got := english.DetectLanguage(); got != "en" {
+		t.Fatalf("expected en but got %q", got)
+	}
+
+	german := &Document{}
+	german.NewChapter("Einleitung").Add(Text("Das Inventarsystem besteht aus einem Anmeldeserver und einer "+
+		"Webanwendung. Es wird von den Mitarbeitern genutzt, um den Bestand zu verwalten, der im Lager ist."),
+		Text("Die Daten sind nicht öffentlich und werden mit einer Verschlüsselung gesichert."))
+	if got := german.DetectLanguage(); got != "de" {
+		t.Fatalf("expected de but got %q", got)
+	}
+
+	short := &Document{Body: []Discriminator{Text("Hello World")}}
+	if got := short.DetectLanguage(); got != "" {
+		t.Fatalf("expected no guess but got %q", got)
+	}
+	if got := (&Document{}).DetectLanguage(); got != "" {
+		t.Fatalf("expected no guess but got %q", got)
+	}
+
+	hash := german.ContentHash()
+	prepare(german)
+	if german.Language != "" || german.ContentHash() != hash {
+		t.Fatalf("expected the language to be untouched but got %q", german.Language)
+	}
+	if got := german.EffectiveLanguage(); got != "de" {
+		t.Fatalf("expected the guessed language but got %q", got)
+	}
+	german.Language = "de-CH"
+	if got := german.EffectiveLanguage(); got != "de-CH" {
+		t.Fatalf("expected the explicit language but got %q", got)
+	}
+	if got := roundTrip(t, german).(*Document); got.Language != "de-CH" {
+		t.Fatalf("expected the language to be kept but got %q", got.Language)
+	}
+}
@@ -0,0 +1,416 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// latexSections are the sectioning commands by chapter level, deeper levels use the last one
+var latexSections = []string{"chapter", "section", "subsection", "subsubsection", "paragraph"}
+
+// latexAlignments are the environments by alignment. Justified text is the default of latex, but the justify
+// environment of ragged2e, which applies \justifying, restores it within another alignment.
+var latexAlignments = map[string]string{
+	AlignLeft:    "flushleft",
+	AlignCenter:  "center",
+	AlignRight:   "flushright",
+	AlignJustify: "justify",
+}
+
+// latexColumns are the tabular column types by alignment
+var latexColumns = map[string]string{
+	AlignLeft:   "l",
+	AlignCenter: "c",
+	AlignRight:  "r",
+}
+
+// latexGroupCommands are the commands by group type, which take the group body as their only argument
+var latexGroupCommands = map[string]string{
+	BoldType:      "textbf",
+	ItalicType:    "textit",
+	UnderlineType: "uline",
+}
+
+// RenderLatex is the built-in renderer, which writes the element and all of its children as a Latex fragment,
+// without any preamble. The required packages are returned by RequiredPreamble.
+func RenderLatex(d Discriminator, out io.Writer) error {
+	r := &latexRenderer{}
+	r.render(d)
+	if _, err := io.WriteString(out, r.sb.String()); err != nil {
+		return fmt.Errorf("unable to write latex: %w", err)
+	}
+	return nil
+}
+
+// renderLatex is the template function variant of RenderLatex
+func renderLatex(d Discriminator) string {
+	r := &latexRenderer{}
+	r.render(d)
+	return r.sb.String()
+}
+
+// renderLatex is the template function variant of RenderLatex, which respects the EmitSourceMap option
+func (p *Template) renderLatex(d Discriminator) string {
+	r := &latexRenderer{sourceMap: p.EmitSourceMap}
+	r.render(d)
+	p.recordFragment(r.sb.String(), r.spans)
+	return r.sb.String()
+}
+
+// latexCallout returns a circled number, which needs no additional package
+func latexCallout(n int) string {
+	return fmt.Sprintf("\\textcircled{\\scriptsize %d}", n)
+}
+
+type latexRenderer struct {
+	sb        strings.Builder
+	sourceMap bool // sourceMap records the spans of all block elements
+	spans     []sourceSpan
+	hiddenTOC bool // hiddenTOC is set within a chapter, which is hidden from the table of contents
+}
+
+func (r *latexRenderer) printf(format string, args ...interface{}) {
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+}
+
+// render writes a single element
+func (r *latexRenderer) render(d Discriminator) {
+	if r.sourceMap && isBlock(d) {
+		start := r.sb.Len()
+		defer func() {
+			r.spans = append(r.spans, sourceSpan{d: d, start: start, end: r.sb.Len()})
+		}()
+	}
+	switch t := d.(type) {
+	case *Workspace:
+		for _, res := range t.Resources {
+			r.render(res)
+		}
+	case *Document:
+		if t.NumberDepth > 0 {
+			r.printf("\\setcounter{secnumdepth}{%d}\n\n", t.NumberDepth)
+		}
+		r.renderBlocks(t.Body)
+	case *Part:
+		r.printf("\\part{%s}\n\n", EscapeLatex(t.Title))
+		r.renderBlocks(t.Body)
+	case *Chapter:
+		cmd := latexSections[len(latexSections)-1]
+		if t.Level >= 0 && t.Level < len(latexSections) {
+			cmd = latexSections[t.Level]
+		}
+		if t.Unnumbered {
+			cmd += "*"
+		}
+		if t.IsLandscape() {
+			r.printf("\\begin{landscape}\n")
+		}
+		// only the outermost hidden chapter suppresses and restores, otherwise a nested one would restore too early
+		hide := t.HideFromTOC && !r.hiddenTOC
+		if hide {
+			// the toc file suppresses all entries until the depth is restored to the value of the preamble
+			r.printf("\\addtocontents{toc}{\\protect\\setcounter{tocdepth}{-2}}\n")
+			r.hiddenTOC = true
+		}
+		r.printf("\\%s{%s}\n\n", cmd, EscapeLatex(t.Title))
+		r.renderBlocks(t.Body)
+		if hide {
+			r.printf("\\addtocontents{toc}{\\protect\\setcounter{tocdepth}{\\arabic{tocdepth}}}\n")
+			r.hiddenTOC = false
+		}
+		if t.IsLandscape() {
+			r.printf("\\end{landscape}\n\n")
+		}
+	case *Span:
+		r.printf("%s", EscapeLatex(t.Value))
+	case *VarRef:
+		r.printf("%s", EscapeLatex(t.Value))
+	case *Code:
+		var opts []string
+		if t.Hint != "" {
+			opts = append(opts, "language="+t.Hint)
+		}
+		if t.Caption != "" {
+			opts = append(opts, "caption={"+EscapeLatex(t.Caption)+"}")
+		}
+		if t.Id != "" {
+			opts = append(opts, "label={"+t.Id+"}")
+		}
+		if len(t.CalloutLines()) > 0 {
+			opts = append(opts, "escapeinside={(*@}{@*)}")
+		}
+		r.printf("\\begin{lstlisting}")
+		if len(opts) > 0 {
+			r.printf("[%s]", strings.Join(opts, ","))
+		}
+		markers := t.calloutMarkers()
+		r.printf("\n")
+		for i, line := range t.VisibleLines() {
+			r.printf("%s", line)
+			if n, ok := markers[i+1]; ok {
+				r.printf(" (*@%s@*)", latexCallout(n))
+			}
+			r.printf("\n")
+		}
+		r.printf("\\end{lstlisting}\n\n")
+		if len(t.CalloutLines()) > 0 {
+			r.printf("\\begin{description}\n")
+			for i, line := range t.CalloutLines() {
+				r.printf("\\item[%s] %s\n", latexCallout(i+1), EscapeLatex(t.Callouts[line]))
+			}
+			r.printf("\\end{description}\n\n")
+		}
+	case *Diff:
+		r.printf("\\begin{lstlisting}\n")
+		for _, l := range t.Lines {
+			r.printf("%s%s\n", l.Prefix(), l.Text)
+		}
+		r.printf("\\end{lstlisting}\n\n")
+	case *Image:
+		if t.Inline {
+			height := t.Height
+			if height == "" {
+				height = "1em"
+			}
+			r.printf("\\raisebox{-0.2\\height}{\\includegraphics[height=%s]{%s}}", height, t.Src)
+			return
+		}
+		width := t.Width
+		if width == "" {
+			width = "\\linewidth"
+		}
+		r.printf("\\begin{figure}")
+		if t.Placement != "" && isValidPlacement(t.Placement) {
+			r.printf("[%s]", t.Placement)
+		}
+		r.printf("\n\\centering\n\\includegraphics[width=%s]{%s}\n\\end{figure}\n\n", width, t.Src)
+	case *List:
+		r.renderList(t)
+	case *ListEntry:
+		r.printf("\\item")
+		if t.IsTask() {
+			if t.IsChecked() {
+				r.printf("[{[x]}]")
+			} else {
+				r.printf("[{[ ]}]")
+			}
+		}
+		r.printf(" ")
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		r.printf("\n")
+	case *Align:
+		env := latexAlignments[t.Alignment]
+		if env == "" {
+			r.renderBlocks(t.Body)
+			return
+		}
+		r.printf("\\begin{%s}\n", env)
+		r.renderBlocks(t.Body)
+		r.printf("\\end{%s}\n\n", env)
+	case *Titlepage:
+		r.printf("\\begin{titlepage}\n")
+		if t.Cover != nil {
+			r.printf("\\begin{tikzpicture}[remember picture,overlay]\n"+
+				"\\node at (current page.center) {\\includegraphics[width=\\paperwidth,height=\\paperheight]{%s}};\n"+
+				"\\end{tikzpicture}\n", t.Cover.Src)
+		}
+		r.renderBlocks(t.Body)
+		r.printf("\\end{titlepage}\n\n")
+	case *RevisionHistory:
+		r.printf("\\newpage\n\\begin{tabular}{llll}\n\\textbf{Version} & \\textbf{Date} & \\textbf{Author} & \\textbf{Changes} \\\\\n\\hline\n")
+		for _, rev := range t.Entries {
+			r.printf("%s & %s & %s & %s \\\\\n", EscapeLatex(rev.Version), EscapeLatex(rev.Date), EscapeLatex(rev.Author), EscapeLatex(rev.Summary))
+		}
+		r.printf("\\end{tabular}\n\\newpage\n\n")
+	case *Table:
+		r.renderTable(t)
+	case *Abbreviation:
+		if t.Long == "" {
+			r.printf("%s", EscapeLatex(t.Short))
+			return
+		}
+		// the acronym package expands the first use itself, see RequiredPreamble for the definitions
+		r.printf("\\ac{%s}", latexAcronymKey(t.Short))
+	case *Embed:
+		r.printf("%s\n\n", WrapURL(t.URL))
+	case *Attachment:
+		if isUrl(t.Src) {
+			r.printf("%s", WrapURL(t.Src))
+			return
+		}
+		r.printf("\\textattachfile")
+		if t.MimeType != "" {
+			r.printf("[mimetype=%s]", t.MimeType)
+		}
+		r.printf("{%s}{%s}", t.OutputPath(), EscapeLatex(t.DisplayLabel()))
+	case *Hyperlink:
+		if t.Href == "" {
+			for _, e := range t.Body {
+				r.render(e)
+			}
+			return
+		}
+		if len(t.Body) == 0 {
+			r.printf("%s", WrapURL(t.Href))
+			return
+		}
+		r.printf("\\href{%s}{", escapeHref(t.Href))
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		r.printf("}")
+	case *Tooltip:
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		if t.Note != "" {
+			r.printf("\\footnote{%s}", EscapeLatex(t.Note))
+		}
+	case *MarginNote:
+		r.printf("\\marginpar{")
+		for _, e := range t.Body {
+			r.render(e)
+		}
+		r.printf("}")
+	case *defaultBody:
+		r.renderGroup(t)
+	default:
+		switch d.Type() {
+		case LineBreakType:
+			r.printf("\\\\\n")
+		case ParagraphBreakType:
+			r.printf("\\par\n")
+		case NewpageType:
+			r.printf("\\newpage\n\n")
+		case TOCType:
+			r.printf("\\tableofcontents\n\n")
+		case AppendixType:
+			r.printf("\\appendix\n\n")
+		default:
+			if c, ok := d.(container); ok {
+				for _, e := range c.children() {
+					r.render(e)
+				}
+			}
+		}
+	}
+}
+
+// renderBlocks writes the body of a document or chapter and ends each paragraph by an empty line
+func (r *latexRenderer) renderBlocks(body []Discriminator) {
+	open := false
+	for _, e := range body {
+		switch {
+		case is(e, ParagraphBreakType) || isBlock(e):
+			if open {
+				r.printf("\n\n")
+				open = false
+			}
+			if isBlock(e) {
+				r.render(e)
+			}
+		default:
+			open = true
+			r.render(e)
+		}
+	}
+	if open {
+		r.printf("\n\n")
+	}
+}
+
+// renderGroup writes the group as the argument of its command, so nested groups are balanced by construction,
+// e.g. \textbf{\textit{x}}. Groups without a command just write their body.
+func (r *latexRenderer) renderGroup(g *defaultBody) {
+	cmd := latexGroupCommands[g.Type()]
+	if cmd != "" {
+		r.printf("\\%s{", cmd)
+	}
+	for _, e := range g.Body {
+		r.render(e)
+	}
+	if cmd != "" {
+		r.printf("}")
+	}
+}
+
+func (r *latexRenderer) renderList(l *List) {
+	env := "itemize"
+	if l.Ordered {
+		env = "enumerate"
+	}
+	r.printf("\\begin{%s}", env)
+	if opts := l.EnumitemOptions(); opts != "" {
+		r.printf("[%s]", opts)
+	}
+	r.printf("\n")
+	for _, item := range l.Items {
+		if _, ok := item.(*ListEntry); ok {
+			r.render(item)
+			continue
+		}
+		r.printf("\\item ")
+		r.render(item)
+		r.printf("\n")
+	}
+	r.printf("\\end{%s}\n\n", env)
+}
+
+func (r *latexRenderer) renderTable(t *Table) {
+	cols := ""
+	for i := 0; i < t.Columns(); i++ {
+		col, ok := latexColumns[t.ColumnAlign(i)]
+		if !ok {
+			col = "l"
+		}
+		cols += col
+	}
+	r.printf("\\begin{table}")
+	if t.Placement != "" && isValidPlacement(t.Placement) {
+		r.printf("[%s]", t.Placement)
+	}
+	r.printf("\n\\centering\n\\begin{tabular}{%s}\n", cols)
+	r.renderTableRows(t.Header)
+	if len(t.Header) > 0 {
+		r.printf("\\hline\n")
+	}
+	r.renderTableRows(t.Rows)
+	r.printf("\\end{tabular}\n")
+	if t.Caption != "" {
+		r.printf("\\caption{%s}\n", EscapeLatex(t.Caption))
+	}
+	r.printf("\\end{table}\n\n")
+}
+
+func (r *latexRenderer) renderTableRows(rows []*TableRow) {
+	for _, row := range rows {
+		for i, cell := range row.Cells {
+			if i > 0 {
+				r.printf(" & ")
+			}
+			for _, e := range cell.Body {
+				r.render(e)
+			}
+		}
+		r.printf(" \\\\\n")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewpage(t *testing.T) {
+	chap := &Chapter{Title: "pages"}
+	chap.Add(Text("a"), Newpage(), Text("b"))
+
+	if got, want := renderHTML(chap), "<section>\n<h2>pages</h2>\n<p>a</p>\n<p>b</p>\n</section>\n"; got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+	if got, want := renderLatex(chap), "\\chapter{pages}\n\na\n\n\\newpage\n\nb\n\n"; got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+}
+
+func TestRenderLatex(t *testing.T) {
+	doc := &Document{}
+	doc.Add(TOC())
+	chap := doc.NewChapter("a & b")
+	chap.Add(Text("50% "), Bold(Text("bold")), LineBreak(), Text("next"))
+	chap.NewChapter("code").Add(&Code{Hint: "Go", Caption: "main", Lines: []string{"x := 1"}})
+	chap.Add(&List{Ordered: true, Start: 3, Items: []Discriminator{ListItem(Text("three")), Task(true, Text("done"))}})
+
+	want := "\\tableofcontents\n\n" +
+		"\\chapter{a \\& b}\n\n" +
+		"50\\% \\textbf{bold}\\\\\nnext\n\n" +
+		"\\section{code}\n\n" +
+		"\\begin{lstlisting}[language=Go,caption={main}]\nx := 1\n\\end{lstlisting}\n\n" +
+		"\\begin{enumerate}[start=3]\n\\item three\n\\item[{[x]}] done\n\\end{enumerate}\n\n"
+	if got := renderLatex(doc); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+}
+
+func TestRenderLatexNesting(t *testing.T) {
+	if got := renderLatex(Bold(Italic(Text("x")))); got != `\textbf{\textit{x}}` {
+		t.Fatal(got)
+	}
+	nested := Underline(Text("a "), Bold(Italic(Text("{b}")), Underline(Text("c"))), Text(" d"))
+	if got := renderLatex(nested); got != `\uline{a \textbf{\textit{\{b\}}\uline{c}} d}` {
+		t.Fatal(got)
+	}
+}
+
+func TestLandscapeChapter(t *testing.T) {
+	doc := &Document{}
+	doc.NewChapter("portrait").Add(Text("a"))
+	wide := doc.NewChapter("wide")
+	wide.Orientation = OrientationLandscape
+	wide.Add(Text("b"))
+
+	doc = roundTrip(t, doc).(*Document)
+	if doc.Body[0].(*Chapter).IsLandscape() || !doc.Body[1].(*Chapter).IsLandscape() {
+		t.Fatal("orientation not preserved")
+	}
+	want := "\\chapter{portrait}\n\na\n\n\\begin{landscape}\n\\chapter{wide}\n\nb\n\n\\end{landscape}\n\n"
+	if got := renderLatex(doc); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+	if got := RequiredPreamble(doc); got != `\usepackage{pdflscape}` {
+		t.Fatal(got)
+	}
+	doc.NumberChapters()
+	if got := tocOf(doc.TableOfContents()); !reflect.DeepEqual(got, []string{"0:portrait=1", "0:wide=2"}) {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// safeSchemes are the url schemes, which are linked by the html renderer
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// A Hyperlink is inline content, which refers to the Href. The body is the visible and possibly styled text of the
+// link, an empty body shows the Href itself. A Href like #id refers to a document or listing, see CheckReferences.
+type Hyperlink struct {
+	Href string
+	Body []Discriminator
+}
+
+// Link creates a hyperlink to the href
+func Link(href string, body ...Discriminator) *Hyperlink {
+	return &Hyperlink{Href: href, Body: body}
+}
+
+func (l *Hyperlink) Add(body ...Discriminator) *Hyperlink {
+	l.Body = append(l.Body, body...)
+	return l
+}
+
+func (l *Hyperlink) Type() string {
+	return LinkType
+}
+
+func (l *Hyperlink) children() []Discriminator {
+	return l.Body
+}
+
+// referenceTarget returns the id of a #id Href
+func (l *Hyperlink) referenceTarget() string {
+	if strings.HasPrefix(l.Href, "#") {
+		return l.Href[1:]
+	}
+	return ""
+}
+
+func (l *Hyperlink) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = l.Type()
+	m["href"] = l.Href
+	m["body"] = toJson(l.Body)
+	return m
+}
+
+func (l *Hyperlink) fromJson(m map[string]interface{}) {
+	l.Href = optString(m, "href")
+	l.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		l.Body = append(l.Body, fromJson(obj))
+	}
+}
+
+// safeHref returns the href, if it is relative or uses one of the safeSchemes, and an empty string otherwise. Like
+// a browser, whitespace and control characters are ignored, so that java\tscript: is detected as well.
+func safeHref(href string) string {
+	clean := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, href)
+	end := strings.IndexAny(clean, ":/?#")
+	if end < 0 || clean[end] != ':' || safeSchemes[strings.ToLower(clean[:end])] {
+		return href
+	}
+	return ""
+}
+
+// hrefOf is the template function, which returns the target of a Hyperlink, an Attachment or an Embed. Any other
+// element has no target.
+func hrefOf(d Discriminator) string {
+	switch t := d.(type) {
+	case *Hyperlink:
+		return t.Href
+	case *Attachment:
+		return t.OutputPath()
+	case *Embed:
+		return t.URL
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	link := Link("https://example.com/a#b", Text("see "), Bold(Text("docs")))
+	if got := roundTrip(t, link); !reflect.DeepEqual(link, got) {
+		t.Fatalf("expected %s but got %s", debugJson(link.toJson()), debugJson(got.toJson()))
+	}
+	empty := &Hyperlink{}
+	if got := roundTrip(t, empty); !reflect.DeepEqual(empty, got) {
+		t.Fatalf("expected an empty link but got %+v", got)
+	}
+	if got := fromJson(map[string]interface{}{typeAttrName: LinkType}).(*Hyperlink); got.Href != "" || got.Body != nil {
+		t.Fatalf("unexpected %+v", got)
+	}
+
+	if html := renderHTML(link); html != `<a href="https://example.com/a#b">see <strong>docs</strong></a>` {
+		t.Fatal(html)
+	}
+	if latex := renderLatex(link); latex != `\href{https://example.com/a\#b}{see \textbf{docs}}` {
+		t.Fatal(latex)
+	}
+	if text := PlainText(Link("https://example.com")); text != "https://example.com" {
+		t.Fatal(text)
+	}
+	if html := renderText(t, `<a href="{{href .}}">`, link); html != `<a href="https://example.com/a#b">` {
+		t.Fatal(html)
+	}
+}
+
+func TestSafeHref(t *testing.T) {
+	for _, href := range []string{"https://example.com", "HTTP://example.com", "mailto:a@example.com", "#intro", "docs/a:b.html", "../index.html", "?q=1"} {
+		if got := safeHref(href); got != href {
+			t.Fatalf("expected %s to be kept but got %q", href, got)
+		}
+	}
+	for _, href := range []string{"javascript:alert(1)", " JavaScript:alert(1)", "java\tscript:alert(1)", "data:text/html,x", "vbscript:x"} {
+		if got := safeHref(href); got != "" {
+			t.Fatalf("expected %q to be rejected but got %q", href, got)
+		}
+	}
+	if html := renderHTML(Link("javascript:alert(1)", Text("click"))); html != "click" {
+		t.Fatal(html)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// A MarginNote is inline content, which is typeset in the margin next to the current line instead of at the end of
+// the page like a footnote. Html renders it as a floating span (see RequiredCSS) and narrow targets, like
+// AsciiDoc, degrade it to a parenthetical.
+type MarginNote struct {
+	Body []Discriminator
+}
+
+// Margin creates a margin note
+func Margin(body ...Discriminator) *MarginNote {
+	return &MarginNote{Body: body}
+}
+
+func (n *MarginNote) Add(body ...Discriminator) *MarginNote {
+	n.Body = append(n.Body, body...)
+	return n
+}
+
+func (n *MarginNote) Type() string {
+	return MarginNoteType
+}
+
+func (n *MarginNote) children() []Discriminator {
+	return n.Body
+}
+
+func (n *MarginNote) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = n.Type()
+	m["body"] = toJson(n.Body)
+	return m
+}
+
+func (n *MarginNote) fromJson(m map[string]interface{}) {
+	n.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		n.Body = append(n.Body, fromJson(obj))
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarginNote(t *testing.T) {
+	note := Margin(Text("see "), Italic(Text("RFC 2119")))
+	if got := roundTrip(t, note); !reflect.DeepEqual(note, got) {
+		t.Fatalf("expected %+v but got %+v", note, got)
+	}
+
+	doc := &Document{Body: []Discriminator{Text("must"), note}}
+	if got := renderLatex(doc); got != "must\\marginpar{see \\textit{RFC 2119}}\n\n" {
+		t.Fatal(got)
+	}
+	if got := renderHTML(note); got != `<span class="marginnote">see <em>RFC 2119</em></span>` {
+		t.Fatal(got)
+	}
+	adoc, err := doc.ToAsciiDoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(adoc); got != "must (see __RFC 2119__)\n" {
+		t.Fatalf("%q", got)
+	}
+}
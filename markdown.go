@@ -0,0 +1,774 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdFence    = regexp.MustCompile("^(```+|~~~+)\\s*([^`\\s]*)")
+	mdListItem = regexp.MustCompile(`^(\s*)([-*+]|(\d+)[.)])\s+(.*)$`)
+	mdTask     = regexp.MustCompile(`^\[([ xX])\]\s+(.*)$`)
+	mdImage    = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+)
+
+// ImportMarkdown parses a CommonMark like markdown document. Headings become nested chapters, fenced code blocks
+// become Code and lists, tasks, images, bold and italic text are supported. Paragraphs are separated by a
+// ParagraphBreak. The document has no title, see PromoteFirstHeading.
+func ImportMarkdown(b []byte) (*Document, error) {
+	p := &mdParser{doc: &Document{}}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		p.line(strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read markdown: %w", err)
+	}
+	p.flush()
+	if p.code != nil {
+		p.add(p.code)
+	}
+	return p.doc, nil
+}
+
+type mdList struct {
+	list   *List
+	indent int
+}
+
+type mdParser struct {
+	doc       *Document
+	chapters  []*Chapter // chapters is the stack of open chapters
+	paragraph []string   // paragraph contains the lines of the current paragraph
+	lists     []mdList   // lists is the stack of open lists
+	code      *Code      // code is the open fenced code block
+	fence     string
+	separate  bool // separate is set, if the next paragraph requires a ParagraphBreak
+}
+
+// add appends the element to the current chapter or document
+func (p *mdParser) add(d Discriminator) {
+	if len(p.chapters) == 0 {
+		p.doc.Add(d)
+		return
+	}
+	p.chapters[len(p.chapters)-1].Add(d)
+}
+
+func (p *mdParser) line(line string) {
+	if p.code != nil {
+		if strings.HasPrefix(strings.TrimSpace(line), p.fence) {
+			p.add(p.code)
+			p.code = nil
+			return
+		}
+		p.code.Lines = append(p.code.Lines, line)
+		return
+	}
+
+	trimmed := strings.TrimSpace(line)
+	if m := mdFence.FindStringSubmatch(trimmed); m != nil {
+		p.flush()
+		p.code = &Code{Hint: m[2]}
+		p.fence = m[1]
+		return
+	}
+	if m := mdHeading.FindStringSubmatch(trimmed); m != nil && !strings.HasPrefix(line, "    ") {
+		p.flush()
+		level := len(m[1]) - 1
+		for len(p.chapters) > 0 && p.chapters[len(p.chapters)-1].Level >= level {
+			p.chapters = p.chapters[:len(p.chapters)-1]
+		}
+		chap := &Chapter{Title: m[2], Level: level}
+		p.add(chap)
+		p.chapters = append(p.chapters, chap)
+		p.separate = false
+		return
+	}
+	if m := mdListItem.FindStringSubmatch(line); m != nil {
+		p.flushParagraph()
+		p.listItem(len(m[1]), m[3] != "", m[3], m[4])
+		return
+	}
+	if trimmed == "" {
+		p.flush()
+		return
+	}
+	if len(p.lists) > 0 && strings.HasPrefix(line, " ") {
+		// a lazy continuation of the last list item
+		items := p.lists[len(p.lists)-1].list.Items
+		entry := items[len(items)-1].(*ListEntry)
+		entry.Add(Text(" "))
+		entry.Add(parseMarkdownInline(trimmed)...)
+		return
+	}
+	p.flushLists()
+	p.paragraph = append(p.paragraph, line)
+}
+
+// listItem appends an item to the list of the given indentation, which is created if required
+func (p *mdParser) listItem(indent int, ordered bool, start string, text string) {
+	for len(p.lists) > 0 && p.lists[len(p.lists)-1].indent > indent {
+		p.lists = p.lists[:len(p.lists)-1]
+	}
+	if len(p.lists) == 0 || p.lists[len(p.lists)-1].indent < indent || p.lists[len(p.lists)-1].list.Ordered != ordered {
+		if len(p.lists) > 0 && p.lists[len(p.lists)-1].indent == indent {
+			p.lists = p.lists[:len(p.lists)-1]
+		}
+		list := &List{Ordered: ordered}
+		if n, err := strconv.Atoi(start); err == nil && ordered && n != 1 {
+			list.Start = n
+		}
+		if len(p.lists) > 0 {
+			parent := p.lists[len(p.lists)-1].list
+			parent.Items[len(parent.Items)-1].(*ListEntry).Add(list)
+		} else {
+			if p.separate {
+				p.add(ParagraphBreak())
+			}
+			p.add(list)
+			p.separate = false
+		}
+		p.lists = append(p.lists, mdList{list: list, indent: indent})
+	}
+	entry := &ListEntry{}
+	if m := mdTask.FindStringSubmatch(text); m != nil {
+		checked := m[1] != " "
+		entry.Checked = &checked
+		text = m[2]
+	}
+	entry.Add(parseMarkdownInline(text)...)
+	p.lists[len(p.lists)-1].list.Add(entry)
+}
+
+// flush closes the current paragraph and all lists
+func (p *mdParser) flush() {
+	p.flushParagraph()
+	p.flushLists()
+}
+
+func (p *mdParser) flushLists() {
+	p.lists = nil
+}
+
+func (p *mdParser) flushParagraph() {
+	if len(p.paragraph) == 0 {
+		return
+	}
+	if len(p.paragraph) == 1 {
+		if m := mdImage.FindStringSubmatch(strings.TrimSpace(p.paragraph[0])); m != nil && m[0] == strings.TrimSpace(p.paragraph[0]) {
+			p.add(&Image{Src: m[2]})
+			p.paragraph = nil
+			p.separate = false
+			return
+		}
+	}
+	if p.separate {
+		p.add(ParagraphBreak())
+	}
+	for i, line := range p.paragraph {
+		hardBreak := strings.HasSuffix(line, "  ") || strings.HasSuffix(line, `\`)
+		line = strings.TrimSpace(strings.TrimSuffix(line, `\`))
+		for _, e := range parseMarkdownInline(line) {
+			p.add(e)
+		}
+		if i < len(p.paragraph)-1 {
+			if hardBreak {
+				p.add(LineBreak())
+			} else {
+				p.add(Text(" "))
+			}
+		}
+	}
+	p.paragraph = nil
+	p.separate = true
+}
+
+// parseMarkdownInline parses emphasis, inline code and images of a single line
+func parseMarkdownInline(s string) []Discriminator {
+	var res []Discriminator
+	text := &strings.Builder{}
+	flushText := func() {
+		if text.Len() > 0 {
+			res = append(res, Text(text.String()))
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '\\' && len(rest) > 1:
+			text.WriteByte(rest[1])
+			i += 2
+			continue
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				text.WriteString(rest[1 : end+1])
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "!["):
+			if m := mdImage.FindStringSubmatch(rest); m != nil {
+				flushText()
+				res = append(res, &Image{Src: m[2]})
+				i += len(m[0])
+				continue
+			}
+		case rest[0] == '_' && i > 0 && isWordByte(s[i-1]):
+			// intraword underscores, like snake_case, are no emphasis
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			if end := strings.Index(rest[2:], rest[:2]); end > 0 {
+				flushText()
+				res = append(res, Bold(parseMarkdownInline(rest[2:end+2])...))
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			if end := strings.IndexByte(rest[1:], rest[0]); end > 0 {
+				flushText()
+				res = append(res, Italic(parseMarkdownInline(rest[1:end+1])...))
+				i += end + 2
+				continue
+			}
+		}
+		text.WriteByte(rest[0])
+		i++
+	}
+	flushText()
+	return res
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// A MarkdownImport configures how a folder of markdown files is imported, see ImportMarkdownDir.
+type MarkdownImport struct {
+	// NestedChapters imports each sub folder as a single document and its files and folders as chapters. Otherwise
+	// each markdown file becomes a separate document, identified by its slash separated path without extension.
+	NestedChapters bool
+}
+
+// ImportMarkdownDir imports each markdown file of the folder recursively as a separate document, ordered by file
+// name. The title of a document is the first heading, if it is the only top level one, otherwise the file name.
+func ImportMarkdownDir(dir string) (*Workspace, error) {
+	return MarkdownImport{}.ImportDir(dir)
+}
+
+// ImportDir imports the markdown files of the folder recursively, ordered by file name. See ImportMarkdownDir.
+func (m MarkdownImport) ImportDir(dir string) (*Workspace, error) {
+	ws := &Workspace{Title: titleOfFile(filepath.Base(dir))}
+	err := m.importDir(ws, dir, "")
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+func (m MarkdownImport) importDir(ws *Workspace, dir string, rel string) error {
+	files, err := ioutil.ReadDir(filepath.Join(dir, rel))
+	if err != nil {
+		return fmt.Errorf("unable to list markdown files from %s: %w", dir, err)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	for _, f := range files {
+		name := path.Join(rel, f.Name())
+		switch {
+		case strings.HasPrefix(f.Name(), "."):
+		case f.IsDir() && m.NestedChapters:
+			doc := &Document{Id: name, Title: titleOfFile(f.Name())}
+			body, err := importChapters(dir, name, 0)
+			if err != nil {
+				return err
+			}
+			doc.Body = body
+			ws.Resources = append(ws.Resources, doc)
+		case f.IsDir():
+			if err := m.importDir(ws, dir, name); err != nil {
+				return err
+			}
+		case isMarkdown(f.Name()):
+			doc, err := importMarkdownFile(dir, name)
+			if err != nil {
+				return err
+			}
+			ws.Resources = append(ws.Resources, doc)
+		}
+	}
+	return nil
+}
+
+// importChapters imports the files and folders of rel as chapters of the given level
+func importChapters(dir string, rel string, level int) ([]Discriminator, error) {
+	files, err := ioutil.ReadDir(filepath.Join(dir, rel))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list markdown files from %s: %w", dir, err)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	var res []Discriminator
+	for _, f := range files {
+		name := path.Join(rel, f.Name())
+		switch {
+		case strings.HasPrefix(f.Name(), "."):
+		case f.IsDir():
+			body, err := importChapters(dir, name, level+1)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, &Chapter{Title: titleOfFile(f.Name()), Level: level, Body: body})
+		case isMarkdown(f.Name()):
+			doc, err := importMarkdownFile(dir, name)
+			if err != nil {
+				return nil, err
+			}
+			shiftLevels(doc.Body, level+1)
+			res = append(res, &Chapter{Title: doc.Title, Level: level, Body: doc.Body})
+		}
+	}
+	return res, nil
+}
+
+// importMarkdownFile imports a single file as a document, identified by its path without extension
+func importMarkdownFile(dir string, rel string) (*Document, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, rel))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", rel, err)
+	}
+	doc, err := ImportMarkdown(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot import %s: %w", rel, err)
+	}
+	doc.Id = strings.TrimSuffix(rel, path.Ext(rel))
+	if !doc.PromoteFirstHeading() {
+		doc.Title = titleOfFile(path.Base(rel))
+	}
+	return doc, nil
+}
+
+func isMarkdown(fname string) bool {
+	ext := strings.ToLower(filepath.Ext(fname))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// titleOfFile derives a title from a file name, e.g. getting-started.md becomes getting started
+func titleOfFile(fname string) string {
+	title := strings.TrimSuffix(fname, filepath.Ext(fname))
+	return strings.NewReplacer("-", " ", "_", " ").Replace(title)
+}
+
+// markdownEscaper escapes the characters of a Span, which have a meaning in CommonMark
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+	"|", `\|`,
+)
+
+// RenderMarkdown is the built-in exporter, which writes the element and all of its children as CommonMark. The
+// document title becomes a level 1 heading and chapters start at level 2, like RenderHTML does. Code becomes a
+// fenced block with the Hint as language, underlined text and tables use inline html and GitHub flavored pipe
+// tables, because CommonMark has no equivalent. Elements without a markdown representation are exported as plain
+// text.
+func RenderMarkdown(d Discriminator, out io.Writer) error {
+	if _, err := io.WriteString(out, renderMarkdown(d)); err != nil {
+		return fmt.Errorf("unable to write markdown: %w", err)
+	}
+	return nil
+}
+
+// renderMarkdown is the template function variant of RenderMarkdown
+func renderMarkdown(d Discriminator) string {
+	r := &markdownRenderer{}
+	r.renderBlocks([]Discriminator{d})
+	return strings.TrimRight(r.sb.String(), "\n") + "\n"
+}
+
+type markdownRenderer struct {
+	sb     strings.Builder
+	indent string // indent is prepended to each line of nested list content
+}
+
+func (r *markdownRenderer) printf(format string, args ...interface{}) {
+	r.sb.WriteString(fmt.Sprintf(format, args...))
+}
+
+// renderBlocks writes consecutive inline elements as a paragraph and separates all blocks by an empty line
+func (r *markdownRenderer) renderBlocks(body []Discriminator) {
+	open := false
+	start := 0
+	closeParagraph := func() {
+		if open {
+			r.endParagraph(start)
+			open = false
+		}
+	}
+	for _, e := range body {
+		switch {
+		case is(e, ParagraphBreakType):
+			closeParagraph()
+		case isBlock(e) || is(e, WorkspaceType) || is(e, DocumentType):
+			closeParagraph()
+			r.renderBlock(e)
+		default:
+			if !open {
+				start = r.sb.Len()
+				open = true
+			}
+			r.renderInline(e)
+		}
+	}
+	closeParagraph()
+}
+
+// endParagraph trims the paragraph, which starts at the given offset, because leading spaces may indicate code
+// and a trailing hard line break would be kept as a backslash.
+func (r *markdownRenderer) endParagraph(start int) {
+	str := r.sb.String()
+	paragraph := strings.TrimSpace(str[start:])
+	// an odd number of trailing backslashes ends with a line break, otherwise they are escaped backslashes
+	for (len(paragraph)-len(strings.TrimRight(paragraph, "\\")))%2 == 1 {
+		paragraph = strings.TrimSpace(paragraph[:len(paragraph)-1])
+	}
+	r.sb.Reset()
+	r.sb.WriteString(str[:start])
+	if paragraph != "" {
+		r.sb.WriteString(paragraph + "\n\n")
+	}
+}
+
+func (r *markdownRenderer) renderBlock(d Discriminator) {
+	switch t := d.(type) {
+	case *Workspace:
+		for _, res := range t.Resources {
+			r.renderBlocks([]Discriminator{res})
+		}
+	case *Document:
+		if t.Title != "" {
+			r.printf("# %s\n\n", markdownEscaper.Replace(t.Title))
+		}
+		r.renderBlocks(t.Body)
+	case *Part:
+		r.printf("# ")
+		if t.Number != "" {
+			r.printf("Part %s: ", markdownEscaper.Replace(t.Number))
+		}
+		r.printf("%s\n\n", markdownEscaper.Replace(t.Title))
+		r.renderBlocks(t.Body)
+	case *Chapter:
+		h := t.Level + 2
+		if h > 6 {
+			h = 6
+		}
+		r.printf("%s ", strings.Repeat("#", h))
+		if t.Number != "" {
+			r.printf("%s ", markdownEscaper.Replace(t.Number))
+		}
+		r.printf("%s\n\n", markdownEscaper.Replace(t.Title))
+		r.renderBlocks(t.Body)
+	case *Code:
+		if t.Caption != "" {
+			r.printf("*%s*\n\n", markdownEscaper.Replace(t.Caption))
+		}
+		r.renderFence(t.Hint, t.VisibleLines())
+	case *Diff:
+		var lines []string
+		for _, line := range t.Lines {
+			lines = append(lines, line.Prefix()+line.Text)
+		}
+		r.renderFence("diff", lines)
+	case *Embed:
+		r.printf("%s\n\n", markdownAutolink(t.URL))
+	case *RevisionHistory:
+		rows := [][]string{{"Version", "Date", "Author", "Changes"}}
+		for _, rev := range t.Entries {
+			rows = append(rows, []string{rev.Version, rev.Date, rev.Author, rev.Summary})
+		}
+		for i, row := range rows {
+			for j, cell := range row {
+				row[j] = markdownEscaper.Replace(cell)
+			}
+			r.printf("| %s |\n", strings.Join(row, " | "))
+			if i == 0 {
+				r.printf("|---|---|---|---|\n")
+			}
+		}
+		r.printf("\n")
+	case *Table:
+		r.renderTable(t)
+	case *Image:
+		r.renderInline(t)
+		r.printf("\n\n")
+	case *List:
+		r.renderList(t)
+		r.printf("\n")
+	case *Align:
+		r.renderBlocks(t.Body)
+	case *Titlepage:
+		if t.Cover != nil {
+			r.printf("![](%s)\n\n", markdownDestination(t.Cover.Src))
+		}
+		r.renderTitlepage(t.Body)
+	case *defaultBody:
+		r.renderBlocks(t.Body)
+	default:
+		switch d.Type() {
+		case TOCType, NewpageType, AppendixType:
+		default:
+			r.printf("%s\n\n", markdownEscaper.Replace(PlainText(d)))
+		}
+	}
+}
+
+// renderTitlepage writes the first inline element as heading, like the title, and each further inline element, like
+// a subtitle, on its own line. Blocks are written as usual.
+func (r *markdownRenderer) renderTitlepage(body []Discriminator) {
+	title := true
+	for _, e := range body {
+		if isBlock(e) {
+			r.renderBlocks([]Discriminator{e})
+			continue
+		}
+		sub := &markdownRenderer{}
+		sub.renderInline(e)
+		line := strings.TrimSpace(sub.sb.String())
+		if line == "" {
+			continue
+		}
+		if title {
+			r.printf("# %s\n\n", strings.ReplaceAll(line, "\\\n", " "))
+			title = false
+			continue
+		}
+		r.printf("%s\n\n", line)
+	}
+}
+
+// renderFence writes the lines as fenced code block, whose fence is longer than any backtick run of the lines
+func (r *markdownRenderer) renderFence(info string, lines []string) {
+	fence := "```"
+	for _, line := range lines {
+		for strings.Contains(line, fence) {
+			fence += "`"
+		}
+	}
+	r.printf("%s%s\n", fence, info)
+	for _, line := range lines {
+		r.printf("%s\n", line)
+	}
+	r.printf("%s\n\n", fence)
+}
+
+func (r *markdownRenderer) renderList(l *List) {
+	for i, item := range l.Items {
+		marker := "-"
+		if l.Ordered {
+			start := l.Start
+			if start == 0 {
+				start = 1
+			}
+			marker = strconv.Itoa(start+i) + "."
+		}
+		r.printf("%s%s ", r.indent, marker)
+		entry, ok := item.(*ListEntry)
+		if !ok {
+			r.renderInline(item)
+			r.printf("\n")
+			continue
+		}
+		if entry.IsTask() {
+			if entry.IsChecked() {
+				r.printf("[x] ")
+			} else {
+				r.printf("[ ] ")
+			}
+		}
+		var nested []*List
+		for _, e := range entry.Body {
+			if list, ok := e.(*List); ok {
+				nested = append(nested, list)
+				continue
+			}
+			r.renderInline(e)
+		}
+		r.printf("\n")
+		parent := r.indent
+		r.indent += strings.Repeat(" ", len(marker)+1)
+		for _, list := range nested {
+			r.renderList(list)
+		}
+		r.indent = parent
+	}
+}
+
+func (r *markdownRenderer) renderInline(d Discriminator) {
+	switch t := d.(type) {
+	case *Span:
+		r.printf("%s", markdownEscaper.Replace(collapseWhitespace(t.Value)))
+	case *Image:
+		r.printf("![](%s)", markdownDestination(t.Src))
+	case *Attachment:
+		r.printf("[%s](%s)", markdownEscaper.Replace(t.DisplayLabel()), markdownDestination(t.OutputPath()))
+	case *Abbreviation:
+		r.printf("%s", markdownEscaper.Replace(t.Text()))
+	case *Hyperlink:
+		switch {
+		case t.Href == "":
+			r.renderGroup("", t.Body, "")
+		case len(t.Body) == 0:
+			r.printf("%s", markdownAutolink(t.Href))
+		default:
+			r.renderGroup("[", t.Body, "]("+markdownDestination(t.Href)+")")
+		}
+	case *Tooltip:
+		r.renderGroup("", t.Body, "")
+		if t.Note != "" {
+			r.printf(" (%s)", markdownEscaper.Replace(t.Note))
+		}
+	case *MarginNote:
+		r.renderGroup(" (", t.Body, ")")
+	case *defaultBody:
+		switch t.Type() {
+		case BoldType:
+			r.renderEmphasis("**", "strong", t.Body)
+		case ItalicType:
+			r.renderEmphasis("*", "em", t.Body)
+		case UnderlineType:
+			r.renderGroup("<u>", t.Body, "</u>")
+		default:
+			r.renderGroup("", t.Body, "")
+		}
+	default:
+		switch d.Type() {
+		case LineBreakType:
+			r.printf("\\\n%s", r.indent)
+		default:
+			r.printf("%s", markdownEscaper.Replace(PlainText(d)))
+		}
+	}
+}
+
+// markdownDestination returns the link destination as is or enclosed in angle brackets, if it contains spaces,
+// parentheses or other characters, which would end a plain destination.
+func markdownDestination(dst string) string {
+	if dst != "" && !strings.ContainsAny(dst, " \t\r\n()<>\\") {
+		return dst
+	}
+	dst = strings.NewReplacer("\\", `\\`, "<", `\<`, ">", `\>`, "\r", "%0D", "\n", "%0A").Replace(dst)
+	return "<" + dst + ">"
+}
+
+// markdownAutolink returns the url as autolink or as a link with the url as text, if it is not a valid autolink
+func markdownAutolink(url string) string {
+	if strings.Contains(url, ":") && !strings.ContainsAny(url, " \t\r\n<>") {
+		return "<" + url + ">"
+	}
+	return "[" + markdownEscaper.Replace(url) + "](" + markdownDestination(url) + ")"
+}
+
+func (r *markdownRenderer) renderGroup(open string, body []Discriminator, close string) {
+	r.printf("%s", open)
+	for _, e := range body {
+		r.renderInline(e)
+	}
+	r.printf("%s", close)
+}
+
+// renderEmphasis writes the body enclosed by the delimiter. CommonMark does not accept a delimiter next to
+// whitespace or punctuation, like in *worl**<u>d</u>***, so the html tag is written instead in that case.
+func (r *markdownRenderer) renderEmphasis(delim string, tag string, body []Discriminator) {
+	sub := &markdownRenderer{indent: r.indent}
+	sub.renderGroup("", body, "")
+	str := sub.sb.String()
+	if str == "" {
+		return
+	}
+	first, _ := utf8.DecodeRuneInString(str)
+	last, _ := utf8.DecodeLastRuneInString(str)
+	if markdownFlanking(first) && markdownFlanking(last) {
+		r.printf("%s%s%s", delim, str, delim)
+		return
+	}
+	r.printf("<%s>%s</%s>", tag, str, tag)
+}
+
+// markdownFlanking returns true, if an emphasis delimiter can be placed next to the character in any context
+func markdownFlanking(c rune) bool {
+	return !unicode.IsSpace(c) && !unicode.IsPunct(c) && !unicode.IsSymbol(c)
+}
+
+// markdownColumns are the delimiter row cells of a pipe table by alignment
+var markdownColumns = map[string]string{
+	AlignLeft:   "---",
+	AlignCenter: ":---:",
+	AlignRight:  "---:",
+}
+
+// renderTable writes a GitHub flavored pipe table. Such a table always has a header, so an empty one is inserted,
+// if required.
+func (r *markdownRenderer) renderTable(t *Table) {
+	if t.Caption != "" {
+		r.printf("*%s*\n\n", markdownEscaper.Replace(t.Caption))
+	}
+	rows := t.AllRows()
+	if len(t.Header) == 0 {
+		rows = append([]*TableRow{{}}, rows...)
+	}
+	var cols []string
+	for i := 0; i < t.Columns(); i++ {
+		cols = append(cols, markdownColumns[t.ColumnAlign(i)])
+	}
+	for i, row := range rows {
+		cells := make([]string, t.Columns())
+		for j, cell := range row.Cells {
+			sub := &markdownRenderer{}
+			for _, e := range cell.Body {
+				sub.renderInline(e)
+			}
+			cells[j] = strings.ReplaceAll(sub.sb.String(), "\\\n", "<br>")
+		}
+		r.printf("| %s |\n", strings.Join(cells, " | "))
+		if i == 0 {
+			r.printf("| %s |\n", strings.Join(cols, " | "))
+		}
+	}
+	r.printf("\n")
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportMarkdown(t *testing.T) {
+	md := "# Title\n\nsome *italic* and **bold** snake_case\nnext line  \nbroken\n\n" +
+		"## Section\n\n- one\n  - nested\n- [x] done\n\n```go\nfunc main() {\n}\n```\n\n![logo](logo.png)\n"
+	doc, err := ImportMarkdown([]byte(md))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Body) != 1 {
+		t.Fatalf("expected a single chapter but got %v", doc.Body)
+	}
+	title := doc.Body[0].(*Chapter)
+	if got := PlainText(&Chapter{Body: title.Body[:len(title.Body)-1]}); got != "some italic and bold snake_case next line\nbroken" {
+		t.Fatalf("unexpected paragraph %q", got)
+	}
+	section := title.Body[len(title.Body)-1].(*Chapter)
+	if section.Title != "Section" || section.Level != 1 {
+		t.Fatalf("unexpected section %+v", section)
+	}
+	var types []string
+	for _, e := range section.Body {
+		types = append(types, e.Type())
+	}
+	if !reflect.DeepEqual(types, []string{ListType, CodeType, ImageType}) {
+		t.Fatalf("unexpected types %v", types)
+	}
+	list := section.Body[0].(*List)
+	if len(list.Items) != 2 || !list.Items[1].(*ListEntry).IsChecked() || len(Collect(list, ListType)) != 2 {
+		t.Fatalf("unexpected list %s", debugJson(list.toJson()))
+	}
+	if code := section.Body[1].(*Code); code.Hint != "go" || len(code.Lines) != 2 {
+		t.Fatalf("unexpected code %+v", code)
+	}
+}
+
+func TestImportMarkdownDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"02-usage.md":              "# Usage\n\ntext",
+		"01-getting-started.md":    "intro",
+		"guide/b.md":               "# B\n\n## Sub\n",
+		"guide/a.md":               "# A\n\n# Two top level headings\n",
+		"guide/advanced/expert.md": "expert",
+		"ignored.txt":              "no markdown",
+	})
+
+	ws, err := ImportMarkdownDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids, titles []string
+	for _, doc := range ws.Documents() {
+		ids = append(ids, doc.Id)
+		titles = append(titles, doc.Title)
+	}
+	if want := []string{"01-getting-started", "02-usage", "guide/a", "guide/advanced/expert", "guide/b"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("expected %v but got %v", want, ids)
+	}
+	if want := []string{"01 getting started", "Usage", "a", "expert", "B"}; !reflect.DeepEqual(titles, want) {
+		t.Fatalf("expected %v but got %v", want, titles)
+	}
+
+	ws, err = MarkdownImport{NestedChapters: true}.ImportDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guide := ws.Documents()[2]
+	if guide.Id != "guide" || len(ws.Documents()) != 3 {
+		t.Fatalf("unexpected documents %v", ws.Documents())
+	}
+	if got := titlesOf(Collect(guide, ChapterType)); !reflect.DeepEqual(got, []string{"a", "A", "Two top level headings", "advanced", "expert", "B", "Sub"}) {
+		t.Fatalf("unexpected chapters %v", got)
+	}
+	if sub := Collect(guide, ChapterType)[6].(*Chapter); sub.Level != 1 {
+		t.Fatalf("expected level 1 but got %d", sub.Level)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrites the golden files of the tests")
+
+func TestRenderMarkdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := RenderMarkdown(createModel(t).Resources[0], buf); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "model.md")
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, buf.Bytes()) {
+		t.Fatalf("expected\n%s\nbut got\n%s", string(want), buf.String())
+	}
+}
+
+func TestRenderMarkdownBlocks(t *testing.T) {
+	doc := &Document{}
+	doc.Add(&Code{Hint: "md", Lines: []string{"```go", "```"}})
+	doc.Add(UnorderedList(ListItem(Text("one"), OrderedList(ListItem(Text("nested")))), Task(true, Text("done"))))
+	doc.Add(Link("https://example.com", Text("a [link]")), Text(" and "), &Image{Src: "a.png", Inline: true})
+	doc.Add(ParagraphBreak())
+	doc.Add(Link("https://en.wikipedia.org/wiki/Go_(language)", Text("wiki")), Text(" "), &Image{Src: "my logo.png", Inline: true})
+	doc.Add(Text(" "), Link("docs/a b.html"))
+
+	want := "````md\n```go\n```\n````\n\n- one\n  1. nested\n- [x] done\n\n" +
+		"[a \\[link\\]](https://example.com) and ![](a.png)\n\n" +
+		"[wiki](<https://en.wikipedia.org/wiki/Go_(language)>) ![](<my logo.png>) [docs/a b.html](<docs/a b.html>)\n"
+	if got := renderMarkdown(doc); got != want {
+		t.Fatalf("expected\n%s\nbut got\n%s", want, got)
+	}
+}
+
+func TestRenderMarkdownEmphasis(t *testing.T) {
+	doc := &Document{}
+	doc.Add(Text("hello "), Italic(Text("worl"), Bold(Underline(Text("d")))), Text(" and "), Bold(Text("bold")))
+	doc.Add(Text(" "), Italic(Text("(note)")), Text(" "), Bold(Italic(Text("both"))))
+
+	want := "hello <em>worl<strong><u>d</u></strong></em> and **bold** <em>(note)</em> <strong>*both*</strong>\n"
+	if got := renderMarkdown(doc); got != want {
+		t.Fatalf("expected\n%s\nbut got\n%s", want, got)
+	}
+}
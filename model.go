@@ -16,6 +16,13 @@
 
 package wdydoc
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // A Discriminator returns a unique type name
 type Discriminator interface {
 	Type() string
@@ -23,12 +30,23 @@ type Discriminator interface {
 	fromJson(map[string]interface{})
 }
 
+// A container is a Discriminator which has child elements
+type container interface {
+	children() []Discriminator
+}
+
 // A workspace contains all resources for different projects, groups whatever.
 type Workspace struct {
 	Format    int
 	Version   string
 	Title     string
 	Resources []Discriminator
+
+	// DefaultAuthors are used for documents without authors, see ApplyDefaultAuthors.
+	DefaultAuthors []*Author
+
+	// Variables are referenced by VarRef elements, e.g. for product names and versions.
+	Variables map[string]string
 }
 
 func (w *Workspace) NewDocument() *Document {
@@ -37,6 +55,33 @@ func (w *Workspace) NewDocument() *Document {
 	return doc
 }
 
+// AddResource appends any top level resource to the workspace.
+func (w *Workspace) AddResource(d Discriminator) {
+	w.Resources = append(w.Resources, d)
+}
+
+// Documents returns all top level documents.
+func (w *Workspace) Documents() []*Document {
+	var res []*Document
+	for _, r := range w.Resources {
+		if doc, ok := r.(*Document); ok {
+			res = append(res, doc)
+		}
+	}
+	return res
+}
+
+// ResourcesOfType returns all top level resources of the given type.
+func (w *Workspace) ResourcesOfType(t string) []Discriminator {
+	var res []Discriminator
+	for _, r := range w.Resources {
+		if r.Type() == t {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
 // ById finds the first component identified by id or returns nil. If id is empty, the workspace itself is returned.
 func (w *Workspace) ById(id string) Discriminator {
 	if id == "" {
@@ -56,6 +101,10 @@ func (w *Workspace) Type() string {
 	return WorkspaceType
 }
 
+func (w *Workspace) children() []Discriminator {
+	return w.Resources
+}
+
 func (w *Workspace) toJson() map[string]interface{} {
 	m := make(map[string]interface{})
 	m[typeAttrName] = w.Type()
@@ -63,17 +112,40 @@ func (w *Workspace) toJson() map[string]interface{} {
 	m["version"] = w.Version
 	m["format"] = w.Format
 	m["resources"] = toJson(w.Resources)
+	if len(w.DefaultAuthors) > 0 {
+		m["defaultAuthors"] = toJson(w.DefaultAuthors)
+	}
+	if len(w.Variables) > 0 {
+		m["variables"] = w.Variables
+	}
 	return m
 }
 
 func (w *Workspace) fromJson(m map[string]interface{}) {
-	w.Title = m["title"].(string)
-	w.Version = m["version"].(string)
+	w.Title = optString(m, "title")
+	w.Version = optString(m, "version")
 	w.Format = optInt(m, "format")
 	w.Resources = nil
 	for _, obj := range assertObjList(m["resources"]) {
 		w.Resources = append(w.Resources, fromJson(obj))
 	}
+	w.DefaultAuthors = nil
+	for _, obj := range assertObjList(m["defaultAuthors"]) {
+		if a, ok := fromJson(obj).(*Author); ok {
+			w.DefaultAuthors = append(w.DefaultAuthors, a)
+		}
+	}
+	w.Variables = optStringMap(m, "variables")
+}
+
+// ApplyDefaultAuthors sets the DefaultAuthors of the workspace for each top level document, which has no authors.
+// Documents with their own authors are not modified.
+func ApplyDefaultAuthors(w *Workspace) {
+	for _, doc := range w.Documents() {
+		if len(doc.Authors) == 0 && len(w.DefaultAuthors) > 0 {
+			doc.Authors = append([]*Author(nil), w.DefaultAuthors...)
+		}
+	}
 }
 
 // A Document contains a markup mixture related to typesetting a book, article or webpage, especially for
@@ -83,6 +155,14 @@ type Document struct {
 	Title   string
 	Authors []*Author
 	Body    []Discriminator
+
+	// Language is the ISO 639-1 code like en or de, e.g. for hyphenation. An empty language is guessed by
+	// EffectiveLanguage.
+	Language string
+
+	// NumberDepth is the deepest chapter level, which is numbered by NumberChapters, like the secnumdepth of Latex.
+	// Deeper chapters are still part of the table of contents. The default 0 numbers all levels.
+	NumberDepth int
 }
 
 func (c *Document) NewChapter(s string) *Chapter {
@@ -94,6 +174,15 @@ func (c *Document) NewChapter(s string) *Chapter {
 	return chap
 }
 
+// CodeBlocks returns all code blocks of the document in document order.
+func (c *Document) CodeBlocks() []*Code {
+	var res []*Code
+	for _, e := range Collect(c, CodeType) {
+		res = append(res, e.(*Code))
+	}
+	return res
+}
+
 func (c *Document) Add(e ...Discriminator) *Document {
 	c.Body = append(c.Body, e...)
 	return c
@@ -103,11 +192,19 @@ func (c *Document) Type() string {
 	return DocumentType
 }
 
+func (c *Document) children() []Discriminator {
+	return c.Body
+}
+
 func (c *Document) toJson() map[string]interface{} {
 	m := make(map[string]interface{})
 	m[typeAttrName] = c.Type()
 	optSet(m, "id", c.Id)
 	m["title"] = c.Title
+	optSet(m, "language", c.Language)
+	if c.NumberDepth > 0 {
+		m["numberDepth"] = c.NumberDepth
+	}
 	m["authors"] = toJson(c.Authors)
 	m["body"] = toJson(c.Body)
 	return m
@@ -116,9 +213,13 @@ func (c *Document) toJson() map[string]interface{} {
 func (c *Document) fromJson(m map[string]interface{}) {
 	c.Title = optString(m, "title")
 	c.Id = optString(m, "id")
+	c.Language = optString(m, "language")
+	c.NumberDepth = optInt(m, "numberDepth")
 	c.Authors = nil
 	for _, obj := range assertObjList(m["authors"]) {
-		c.Authors = append(c.Authors, fromJson(obj).(*Author))
+		if a, ok := fromJson(obj).(*Author); ok {
+			c.Authors = append(c.Authors, a)
+		}
 	}
 	c.Body = nil
 	for _, obj := range assertObjList(m["body"]) {
@@ -131,6 +232,28 @@ type Author struct {
 	Firstname string
 	Lastname  string
 	EMail     string
+	Links     map[string]string // Links contains further contacts by kind, e.g. web or github
+	AvatarURL string            // AvatarURL is optional, templates should fallback to the Initials
+}
+
+// Initials returns the uppercase first letters of the first and the last word of the authors name, e.g. AB for
+// Anna Maria van der Berg. A single word name results in its first two letters.
+func (a *Author) Initials() string {
+	words := strings.Fields(a.Firstname + " " + a.Lastname)
+	switch len(words) {
+	case 0:
+		return ""
+	case 1:
+		runes := []rune(words[0])
+		if len(runes) > 2 {
+			runes = runes[:2]
+		}
+		return strings.ToUpper(string(runes))
+	default:
+		first := []rune(words[0])[0]
+		last := []rune(words[len(words)-1])[0]
+		return strings.ToUpper(string([]rune{first, last}))
+	}
 }
 
 func (a *Author) Type() string {
@@ -143,20 +266,41 @@ func (a *Author) toJson() map[string]interface{} {
 	m["firstname"] = a.Firstname
 	m["lastname"] = a.Lastname
 	m["email"] = a.EMail
+	if len(a.Links) > 0 {
+		m["links"] = a.Links
+	}
+	optSet(m, "avatarUrl", a.AvatarURL)
 	return m
 }
 
 func (a *Author) fromJson(m map[string]interface{}) {
-	a.Firstname = m["firstname"].(string)
-	a.Lastname = m["lastname"].(string)
-	a.EMail = m["email"].(string)
+	a.Firstname = optString(m, "firstname")
+	a.Lastname = optString(m, "lastname")
+	a.EMail = optString(m, "email")
+	a.Links = optStringMap(m, "links")
+	a.AvatarURL = optString(m, "avatarUrl")
 }
 
 // A Chapter allows the hierarchical titled grouping. Better to keep the level consistent with the hierarchy.
 type Chapter struct {
-	Title string
-	Level int // start by 0 and keep consistent
-	Body  []Discriminator
+	Title       string
+	Level       int    // start by 0 and keep consistent
+	Number      string // Number is not serialized but calculated by Document.NumberChapters, e.g. 1.2 or A.1
+	Unnumbered  bool   // Unnumbered chapters like a preface are still part of the table of contents but have no Number
+	HideFromTOC bool   // HideFromTOC omits the chapter and its sub chapters from the table of contents, but not the body
+	Orientation string // Orientation is either OrientationPortrait (the default) or OrientationLandscape, e.g. for wide tables
+	Body        []Discriminator
+}
+
+// The page orientations of a Chapter
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// IsLandscape returns true, if the pages of the chapter are rotated
+func (c *Chapter) IsLandscape() bool {
+	return c.Orientation == OrientationLandscape
 }
 
 func (c *Chapter) Add(e ...Discriminator) *Chapter {
@@ -182,11 +326,22 @@ func (c *Chapter) Type() string {
 	return ChapterType
 }
 
+func (c *Chapter) children() []Discriminator {
+	return c.Body
+}
+
 func (c *Chapter) toJson() map[string]interface{} {
 	m := make(map[string]interface{})
 	m[typeAttrName] = c.Type()
 	m["title"] = c.Title
 	m["level"] = c.Level
+	if c.Unnumbered {
+		m["unnumbered"] = true
+	}
+	if c.HideFromTOC {
+		m["hideFromToc"] = true
+	}
+	optSet(m, "orientation", c.Orientation)
 	m["body"] = toJson(c.Body)
 	return m
 }
@@ -194,6 +349,9 @@ func (c *Chapter) toJson() map[string]interface{} {
 func (c *Chapter) fromJson(m map[string]interface{}) {
 	c.Title = optString(m, "title")
 	c.Level = optInt(m, "level")
+	c.Unnumbered = optBool(m, "unnumbered")
+	c.HideFromTOC = optBool(m, "hideFromToc")
+	c.Orientation = optString(m, "orientation")
 	c.Body = nil
 	for _, obj := range assertObjList(m["body"]) {
 		c.Body = append(c.Body, fromJson(obj))
@@ -205,9 +363,24 @@ func Newpage() Discriminator {
 	return defaultType{name: NewpageType}
 }
 
-// Newline creates a new line element
+// Newline creates a line break element and is an alias of LineBreak
 func Newline() Discriminator {
-	return defaultType{name: NewlineType}
+	return LineBreak()
+}
+
+// LineBreak continues the text in a new line of the same paragraph, like <br> or \\ in Latex
+func LineBreak() Discriminator {
+	return defaultType{name: LineBreakType}
+}
+
+// ParagraphBreak ends the current paragraph and starts a new one, like <p> or \par in Latex
+func ParagraphBreak() Discriminator {
+	return defaultType{name: ParagraphBreakType}
+}
+
+// Appendix marks the end of the main chapters. All following chapters are appendices, which are numbered by letters.
+func Appendix() Discriminator {
+	return defaultType{name: AppendixType}
 }
 
 // TOC creates a table of contents based on chapters and their according levels
@@ -233,8 +406,53 @@ func Underline(body ...Discriminator) *defaultBody {
 // A TitlePage is a specially formatted page with a certain meaning.
 // The interpretation of the body depends largely on the actual template
 // and may put everything or nothing or just the first text.
-func TitlePage(body ...Discriminator) *defaultBody {
-	return &defaultBody{name: TitlepageType, Body: body}
+func TitlePage(body ...Discriminator) *Titlepage {
+	return &Titlepage{Body: body}
+}
+
+// A Titlepage is created by TitlePage. The optional Cover is a full-bleed image behind the body.
+type Titlepage struct {
+	Cover *Image
+	Body  []Discriminator
+}
+
+func (t *Titlepage) Add(body ...Discriminator) *Titlepage {
+	t.Body = append(t.Body, body...)
+	return t
+}
+
+func (t *Titlepage) Type() string {
+	return TitlepageType
+}
+
+// children returns the cover first, so that it is checked like any other image
+func (t *Titlepage) children() []Discriminator {
+	if t.Cover == nil {
+		return t.Body
+	}
+	return append([]Discriminator{t.Cover}, t.Body...)
+}
+
+func (t *Titlepage) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = t.Type()
+	if t.Cover != nil {
+		m["cover"] = t.Cover.toJson()
+	}
+	m["body"] = toJson(t.Body)
+	return m
+}
+
+func (t *Titlepage) fromJson(m map[string]interface{}) {
+	t.Cover = nil
+	if obj, ok := m["cover"].(map[string]interface{}); ok {
+		t.Cover = &Image{}
+		t.Cover.fromJson(obj)
+	}
+	t.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		t.Body = append(t.Body, fromJson(obj))
+	}
 }
 
 type Span struct {
@@ -245,6 +463,18 @@ func (t *Span) String() string {
 	return t.Value
 }
 
+// SetValue replaces the text
+func (t *Span) SetValue(str string) *Span {
+	t.Value = str
+	return t
+}
+
+// Append adds the text to the end of the current value, without any separator
+func (t *Span) Append(str string) *Span {
+	t.Value += str
+	return t
+}
+
 func (t *Span) Type() string {
 	return TextType
 }
@@ -260,14 +490,69 @@ func (t *Span) fromJson(m map[string]interface{}) {
 	t.Value = optString(m, "value")
 }
 
-func Text(str string) *Span {
-	return &Span{str}
+// Text creates a new span from the given strings, which are joined by a single space
+func Text(str ...string) *Span {
+	return &Span{strings.Join(str, " ")}
 }
 
 // A Code element contains a bunch of lines and a type hint
 type Code struct {
-	Hint  string //
-	Lines []string
+	Id      string // Id is optional and identifies the listing for references
+	Hint    string //
+	Caption string // Caption is optional, only captioned code is numbered as a listing
+	Lines   []string
+	Number  int // Number is not serialized but calculated by Document.NumberFloats, e.g. 2 for Listing 2
+
+	// MaxLines truncates the rendered lines, see VisibleLines. The model always contains all lines.
+	MaxLines int
+
+	// Callouts annotate lines by their 1-based line number. Renderers mark the lines with numbered markers and
+	// list the notes below the block.
+	Callouts map[int]string
+}
+
+// CalloutLines returns the annotated line numbers in ascending order. The marker of a line is its index + 1.
+// Lines, which are not rendered, like the ones truncated by MaxLines, are skipped, because there is nothing to mark.
+func (c *Code) CalloutLines() []int {
+	visible := len(c.Lines)
+	if c.MaxLines > 0 && visible > c.MaxLines {
+		visible = c.MaxLines
+	}
+	res := make([]int, 0, len(c.Callouts))
+	for line := range c.Callouts {
+		if line >= 1 && line <= visible {
+			res = append(res, line)
+		}
+	}
+	sort.Ints(res)
+	return res
+}
+
+// calloutMarkers maps each annotated line number to its marker
+func (c *Code) calloutMarkers() map[int]int {
+	res := make(map[int]int, len(c.Callouts))
+	for i, line := range c.CalloutLines() {
+		res[line] = i + 1
+	}
+	return res
+}
+
+// calloutMarker returns a dingbat like ❶ for the markers 1 to 10 and (11) otherwise
+func calloutMarker(n int) string {
+	if n >= 1 && n <= 10 {
+		return string(rune('\u2776' + n - 1))
+	}
+	return fmt.Sprintf("(%d)", n)
+}
+
+// VisibleLines returns the lines to render. If there are more than MaxLines, only MaxLines are returned and
+// a marker like "... 42 more lines" is appended.
+func (c *Code) VisibleLines() []string {
+	if c.MaxLines <= 0 || len(c.Lines) <= c.MaxLines {
+		return c.Lines
+	}
+	res := append([]string(nil), c.Lines[:c.MaxLines]...)
+	return append(res, fmt.Sprintf("... %d more lines", len(c.Lines)-c.MaxLines))
 }
 
 func (c *Code) Type() string {
@@ -277,14 +562,26 @@ func (c *Code) Type() string {
 func (c *Code) toJson() map[string]interface{} {
 	m := make(map[string]interface{})
 	m[typeAttrName] = c.Type()
+	optSet(m, "id", c.Id)
 	m["hint"] = c.Hint
+	optSet(m, "caption", c.Caption)
 	m["lines"] = c.Lines
+	if c.MaxLines > 0 {
+		m["maxLines"] = c.MaxLines
+	}
+	if len(c.Callouts) > 0 {
+		m["callouts"] = intMap(c.Callouts)
+	}
 	return m
 }
 
 func (c *Code) fromJson(m map[string]interface{}) {
+	c.Id = optString(m, "id")
 	c.Hint = optString(m, "hint")
+	c.Caption = optString(m, "caption")
+	c.MaxLines = optInt(m, "maxLines")
 	c.Lines = optStringSlice(m, "lines")
+	c.Callouts = optIntMap(m, "callouts")
 }
 
 // An Image element contains a reference (filename) to a usually local image
@@ -292,6 +589,13 @@ type Image struct {
 	Src    string
 	Width  string
 	Height string
+	Number int // Number is not serialized but calculated by Document.NumberFloats, e.g. 3 for Figure 3
+
+	// Placement is an optional latex float specifier made of h, t, b, p and !, e.g. htbp. Html ignores it.
+	Placement string
+
+	// Inline images, like icons, are part of the text and neither a figure nor numbered.
+	Inline bool
 }
 
 func (c *Image) Type() string {
@@ -304,6 +608,10 @@ func (c *Image) toJson() map[string]interface{} {
 	m["src"] = c.Src
 	m["width"] = c.Width
 	m["height"] = c.Height
+	optSet(m, "placement", c.Placement)
+	if c.Inline {
+		m["inline"] = true
+	}
 	return m
 }
 
@@ -311,4 +619,146 @@ func (c *Image) fromJson(m map[string]interface{}) {
 	c.Src = optString(m, "src")
 	c.Width = optString(m, "width")
 	c.Height = optString(m, "height")
+	c.Placement = optString(m, "placement")
+	c.Inline = optBool(m, "inline")
+}
+
+// A List contains items which are either typeset with bullets or ascending numbers.
+type List struct {
+	Ordered bool
+	Start   int    // Start is the first number of an ordered list, the default 0 means to start with 1
+	Marker  string // Marker is a css list-style-type like disc, circle, square, decimal, lower-alpha or upper-roman
+	Items   []Discriminator
+}
+
+// UnorderedList creates a list with bullets
+func UnorderedList(items ...Discriminator) *List {
+	return &List{Items: items}
+}
+
+// OrderedList creates a numbered list
+func OrderedList(items ...Discriminator) *List {
+	return &List{Ordered: true, Items: items}
+}
+
+// Add appends the items to the list
+func (l *List) Add(items ...Discriminator) *List {
+	l.Items = append(l.Items, items...)
+	return l
+}
+
+// EnumitemOptions returns the options for a Latex enumerate or itemize environment of the enumitem package, to
+// represent Start and Marker, e.g. start=5,label=\alph*. for a lower-alpha list starting at 5.
+func (l *List) EnumitemOptions() string {
+	var opts []string
+	if l.Ordered && l.Start != 0 {
+		opts = append(opts, "start="+strconv.Itoa(l.Start))
+	}
+	if label, ok := enumitemLabels[l.Marker]; ok {
+		opts = append(opts, "label="+label)
+	}
+	return strings.Join(opts, ",")
+}
+
+// enumitemLabels maps css list-style-types to enumitem labels
+var enumitemLabels = map[string]string{
+	"disc":        `\textbullet`,
+	"circle":      `$\circ$`,
+	"square":      `\rule{0.8ex}{0.8ex}`,
+	"none":        `{}`,
+	"decimal":     `\arabic*.`,
+	"lower-alpha": `\alph*.`,
+	"upper-alpha": `\Alph*.`,
+	"lower-roman": `\roman*.`,
+	"upper-roman": `\Roman*.`,
+}
+
+func (l *List) Type() string {
+	return ListType
+}
+
+func (l *List) children() []Discriminator {
+	return l.Items
+}
+
+func (l *List) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = l.Type()
+	m["ordered"] = l.Ordered
+	m["start"] = l.Start
+	optSet(m, "marker", l.Marker)
+	m["items"] = toJson(l.Items)
+	return m
+}
+
+func (l *List) fromJson(m map[string]interface{}) {
+	l.Ordered = optBool(m, "ordered")
+	l.Start = optInt(m, "start")
+	l.Marker = optString(m, "marker")
+	l.Items = nil
+	for _, obj := range assertObjList(m["items"]) {
+		l.Items = append(l.Items, fromJson(obj))
+	}
+}
+
+// A ListEntry is a single item of a List which may contain arbitrary content, even other lists. If Checked is
+// not nil, the entry is a task which is typeset with a checkbox.
+type ListEntry struct {
+	Checked *bool // Checked is nil for ordinary entries, otherwise it is the state of the checkbox
+	Body    []Discriminator
+}
+
+// ListItem creates an ordinary entry for a List
+func ListItem(body ...Discriminator) *ListEntry {
+	return &ListEntry{Body: body}
+}
+
+// Task creates a list entry with a checkbox
+func Task(checked bool, body ...Discriminator) *ListEntry {
+	return &ListEntry{Checked: &checked, Body: body}
+}
+
+// IsTask returns true, if the entry has a checkbox
+func (e *ListEntry) IsTask() bool {
+	return e.Checked != nil
+}
+
+// IsChecked returns true, if the entry has a checked checkbox. Templates should use this instead of Checked,
+// because a pointer to false is also true within a template condition.
+func (e *ListEntry) IsChecked() bool {
+	return e.Checked != nil && *e.Checked
+}
+
+func (e *ListEntry) Add(body ...Discriminator) *ListEntry {
+	e.Body = append(e.Body, body...)
+	return e
+}
+
+func (e *ListEntry) Type() string {
+	return ListItemType
+}
+
+func (e *ListEntry) children() []Discriminator {
+	return e.Body
+}
+
+func (e *ListEntry) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = e.Type()
+	if e.Checked != nil {
+		m["checked"] = *e.Checked
+	}
+	m["body"] = toJson(e.Body)
+	return m
+}
+
+func (e *ListEntry) fromJson(m map[string]interface{}) {
+	e.Checked = nil
+	if checked, ok := m["checked"].(bool); ok {
+		e.Checked = &checked
+	}
+	e.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		e.Body = append(e.Body, fromJson(obj))
+	}
 }
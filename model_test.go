@@ -0,0 +1,344 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTrip encodes the element into json bytes and decodes it again.
+func roundTrip(t *testing.T, d Discriminator) Discriminator {
+	t.Helper()
+	b, err := json.Marshal(d.toJson())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	return fromJson(m)
+}
+
+// renderText applies the text template on the model and returns the result.
+func renderText(t *testing.T, tpl string, model interface{}) string {
+	t.Helper()
+	dir := writeFiles(t, map[string]string{"out.txt.tmpl": tpl})
+	buildDir := t.TempDir()
+	p, err := ReadTemplate(dir, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := p.Build(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected a single file but got %v", files)
+	}
+	b, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestListRoundTrip(t *testing.T) {
+	list := OrderedList(Text("a"), Text("b"))
+	list.Start = 5
+	list.Marker = "lower-alpha"
+	got := roundTrip(t, list)
+	if !reflect.DeepEqual(list, got) {
+		t.Fatalf("expected %s but got %s", debugJson(list.toJson()), debugJson(got.toJson()))
+	}
+
+	plain := roundTrip(t, UnorderedList(Text("x"))).(*List)
+	if plain.Ordered || plain.Start != 0 || plain.Marker != "" {
+		t.Fatalf("unexpected defaults: %+v", plain)
+	}
+}
+
+func TestNestedListRoundTrip(t *testing.T) {
+	list := UnorderedList(
+		ListItem(Text("first"), OrderedList(ListItem(Bold(Text("a"))), ListItem(Text("b")))),
+		ListItem(Text("second")),
+	)
+	got := roundTrip(t, list)
+	if !reflect.DeepEqual(list, got) {
+		t.Fatalf("expected %s but got %s", debugJson(list.toJson()), debugJson(got.toJson()))
+	}
+	if lists := Collect(got, ListType); len(lists) != 2 || !lists[1].(*List).Ordered {
+		t.Fatalf("expected the nested ordered list but got %v", lists)
+	}
+
+	got = got.(*List).Items[0].(*ListEntry).Body[1]
+	if text := renderText(t, `{{range .Items}}[{{range .Body}}{{.Type}}{{end}}]{{end}}`, got); text != "[bold][text]" {
+		t.Fatal(text)
+	}
+}
+
+func TestListStart(t *testing.T) {
+	list := OrderedList(Text("five"), Text("six"))
+	list.Start = 5
+	list.Marker = "upper-roman"
+
+	html := renderText(t, `<ol start="{{.Start}}" style="list-style-type: {{.Marker}}">{{range .Items}}<li>{{str .}}</li>{{end}}</ol>`, list)
+	if html != `<ol start="5" style="list-style-type: upper-roman"><li>five</li><li>six</li></ol>` {
+		t.Fatal(html)
+	}
+
+	latex := renderText(t, `\begin{enumerate}[{{.EnumitemOptions}}]`, list)
+	if latex != `\begin{enumerate}[start=5,label=\Roman*.]` {
+		t.Fatal(latex)
+	}
+
+	if opts := UnorderedList().EnumitemOptions(); opts != "" {
+		t.Fatalf("expected no options but got %s", opts)
+	}
+}
+
+func TestTaskRoundTrip(t *testing.T) {
+	list := UnorderedList(ListItem(Text("plain")), Task(true, Text("done")), Task(false, Text("todo")))
+	got := roundTrip(t, list).(*List)
+	if !reflect.DeepEqual(list, got) {
+		t.Fatalf("expected %s but got %s", debugJson(list.toJson()), debugJson(got.toJson()))
+	}
+
+	states := ""
+	for _, item := range got.Items {
+		e := item.(*ListEntry)
+		switch {
+		case !e.IsTask():
+			states += "-"
+		case e.IsChecked():
+			states += "x"
+		default:
+			states += "o"
+		}
+	}
+	if states != "-xo" {
+		t.Fatalf("unexpected states %s", states)
+	}
+
+	html := renderText(t, `{{range .Items}}{{if .IsTask}}<input type="checkbox" disabled{{if .IsChecked}} checked{{end}}>{{end}}{{end}}`, list)
+	if html != `<input type="checkbox" disabled checked><input type="checkbox" disabled>` {
+		t.Fatal(html)
+	}
+}
+
+func TestAuthorLinksRoundTrip(t *testing.T) {
+	author := &Author{
+		Firstname: "Torben",
+		Lastname:  "Schinke",
+		EMail:     "mail@example.com",
+		Links:     map[string]string{"web": "https://example.com", "github": "torbenschinke"},
+	}
+	if got := roundTrip(t, author); !reflect.DeepEqual(author, got) {
+		t.Fatalf("expected %+v but got %+v", author, got)
+	}
+
+	old := &Author{Firstname: "Ada", EMail: "ada@example.com"}
+	if got := roundTrip(t, old); !reflect.DeepEqual(old, got) {
+		t.Fatalf("expected %+v but got %+v", old, got)
+	}
+}
+
+func TestWorkspaceResources(t *testing.T) {
+	ws := &Workspace{}
+	doc := ws.NewDocument()
+	other := &Document{Id: "other"}
+	ws.AddResource(other)
+	ws.AddResource(&Author{Firstname: "not a document"})
+
+	if docs := ws.Documents(); len(docs) != 2 || docs[0] != doc || docs[1] != other {
+		t.Fatalf("unexpected documents %v", docs)
+	}
+	if authors := ws.ResourcesOfType(AuthorType); len(authors) != 1 {
+		t.Fatalf("unexpected authors %v", authors)
+	}
+	if res := ws.ResourcesOfType(ChapterType); len(res) != 0 {
+		t.Fatalf("unexpected chapters %v", res)
+	}
+}
+
+func TestDefaultAuthors(t *testing.T) {
+	ws := &Workspace{DefaultAuthors: []*Author{{Firstname: "Default"}}}
+	inherits := ws.NewDocument()
+	own := ws.NewDocument()
+	own.Authors = []*Author{{Firstname: "Own"}}
+
+	ws = roundTrip(t, ws).(*Workspace)
+	if len(ws.DefaultAuthors) != 1 || ws.DefaultAuthors[0].Firstname != "Default" {
+		t.Fatalf("default authors not persisted: %v", ws.DefaultAuthors)
+	}
+	ApplyDefaultAuthors(ws)
+	inherits, own = ws.Documents()[0], ws.Documents()[1]
+	if len(inherits.Authors) != 1 || inherits.Authors[0].Firstname != "Default" {
+		t.Fatalf("expected the default author but got %v", inherits.Authors)
+	}
+	if len(own.Authors) != 1 || own.Authors[0].Firstname != "Own" {
+		t.Fatalf("expected the own author but got %v", own.Authors)
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		author *Author
+		want   string
+	}{
+		{&Author{Firstname: "torben", Lastname: "Schinke"}, "TS"},
+		{&Author{Firstname: "Anna Maria", Lastname: "van der Berg"}, "AB"},
+		{&Author{Firstname: "Plato"}, "PL"},
+		{&Author{Lastname: "Ö"}, "Ö"},
+		{&Author{Firstname: " ", Lastname: ""}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.author.Initials(); got != tt.want {
+			t.Errorf("expected %q but got %q for %+v", tt.want, got, tt.author)
+		}
+	}
+
+	author := &Author{Firstname: "Ada", AvatarURL: "https://example.com/ada.png"}
+	if got := roundTrip(t, author); !reflect.DeepEqual(author, got) {
+		t.Fatalf("expected %+v but got %+v", author, got)
+	}
+	if got := renderText(t, `{{initials .}}`, &Author{Firstname: "Ada", Lastname: "Lovelace"}); got != "AL" {
+		t.Fatal(got)
+	}
+}
+
+func TestCodeMaxLines(t *testing.T) {
+	code := &Code{Lines: []string{"1", "2", "3", "4", "5"}, MaxLines: 3}
+	want := []string{"1", "2", "3", "... 2 more lines"}
+	if got := code.VisibleLines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	if len(code.Lines) != 5 {
+		t.Fatal("the model must keep all lines")
+	}
+	if got := renderHTML(code); got != "<pre><code>1\n2\n3\n... 2 more lines</code></pre>\n" {
+		t.Fatal(got)
+	}
+	if res := roundTrip(t, code).(*Code); res.MaxLines != 3 {
+		t.Fatalf("expected max lines but got %+v", res)
+	}
+
+	code.MaxLines = 0
+	if got := code.VisibleLines(); len(got) != 5 {
+		t.Fatalf("expected all lines but got %v", got)
+	}
+
+	large := &Code{Lines: make([]string, codeLinesWarning+1)}
+	errs := CheckCodeSizes(&Document{Id: "doc", Body: []Discriminator{code, large}})
+	if len(errs) != 1 || !errs[0].(*ValidationError).Warning {
+		t.Fatalf("expected a single warning but got %v", errs)
+	}
+}
+
+func TestCodeCallouts(t *testing.T) {
+	code := &Code{Hint: "go", Lines: []string{"a", "b", "c := New()"}, Callouts: map[int]string{3: "initializes the client"}}
+	if res := roundTrip(t, code).(*Code); !reflect.DeepEqual(res.Callouts, code.Callouts) {
+		t.Fatalf("expected %v but got %v", code.Callouts, res.Callouts)
+	}
+
+	wantHTML := "<pre><code class=\"language-go\">a\nb\nc := New() <span class=\"callout\">❶</span></code></pre>\n" +
+		"<dl class=\"callouts\">\n<dt>❶</dt><dd>initializes the client</dd>\n</dl>\n"
+	if got := renderHTML(code); got != wantHTML {
+		t.Fatal(got)
+	}
+	if got := renderLatex(code); !strings.Contains(got, "c := New() (*@\\textcircled{\\scriptsize 1}@*)\n\\end{lstlisting}") ||
+		!strings.Contains(got, "\\item[\\textcircled{\\scriptsize 1}] initializes the client") {
+		t.Fatal(got)
+	}
+
+	code.MaxLines = 2
+	wantHTML = "<pre><code class=\"language-go\">a\nb\n... 1 more lines</code></pre>\n"
+	if got := renderHTML(code); got != wantHTML {
+		t.Fatalf("expected no callout on the truncation marker but got %s", got)
+	}
+	if got := renderLatex(code); strings.Contains(got, "textcircled") || strings.Contains(got, "escapeinside") {
+		t.Fatal(got)
+	}
+	adoc, err := (&Document{Body: []Discriminator{code}}).ToAsciiDoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(adoc), "<1>") {
+		t.Fatal(string(adoc))
+	}
+}
+
+func TestTitlePageCover(t *testing.T) {
+	page := TitlePage(Text("my book"))
+	page.Cover = &Image{Src: "cover.png"}
+	got := roundTrip(t, page).(*Titlepage)
+	if !reflect.DeepEqual(page, got) {
+		t.Fatalf("expected %+v but got %+v", page, got)
+	}
+	if plain := roundTrip(t, TitlePage(Text("x"))).(*Titlepage); plain.Cover != nil || len(plain.Body) != 1 {
+		t.Fatalf("expected no cover but got %+v", plain)
+	}
+
+	doc := &Document{Body: []Discriminator{page, &Image{Src: "figure.png"}}}
+	doc.NumberFloats()
+	if page.Cover.Number != 0 || doc.Body[1].(*Image).Number != 1 {
+		t.Fatal("a cover must not be numbered as a figure")
+	}
+	if got := renderHTML(page); got != "<header class=\"titlepage\">\n<img class=\"cover\" src=\"cover.png\">\n<p>my book</p>\n</header>\n" {
+		t.Fatal(got)
+	}
+}
+
+func TestCodeLinesRoundTrip(t *testing.T) {
+	ws := &Workspace{}
+	code := &Code{Hint: "go", Lines: []string{"package main", "", "func main() {}"}}
+	ws.NewDocument().NewChapter("chap").Add(code)
+	b, err := Marshal(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := Collect(res, CodeType)
+	if len(blocks) != 1 || !reflect.DeepEqual(blocks[0].(*Code).Lines, code.Lines) {
+		t.Fatalf("expected %v but got %v", code.Lines, blocks)
+	}
+}
+
+func TestSpanText(t *testing.T) {
+	if got := Text("hello", "wide", "world").Value; got != "hello wide world" {
+		t.Fatalf("expected joined text but got '%s'", got)
+	}
+	if got := Text().Value; got != "" {
+		t.Fatalf("expected empty text but got '%s'", got)
+	}
+
+	span := Text("hello")
+	span.Append(", ").Append("world")
+	if span.Value != "hello, world" {
+		t.Fatalf("expected appended text but got '%s'", span.Value)
+	}
+	if span.SetValue("bye").String() != "bye" {
+		t.Fatalf("expected replaced text but got '%s'", span.Value)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// navigation contains the parent and position of each element of a tree, see newNavigation
+type navigation struct {
+	entries map[uintptr]navEntry
+}
+
+type navEntry struct {
+	parent Discriminator
+	index  int
+}
+
+// newNavigation calculates the parents and sibling indices of all elements, which are referenced by pointer.
+func newNavigation(root Discriminator) *navigation {
+	n := &navigation{entries: make(map[uintptr]navEntry)}
+	Walk(root, func(d Discriminator) bool {
+		c, ok := d.(container)
+		if !ok {
+			return true
+		}
+		for i, child := range c.children() {
+			if key, ok := identity(child); ok {
+				if _, exists := n.entries[key]; !exists {
+					n.entries[key] = navEntry{parent: d, index: i}
+				}
+			}
+		}
+		return true
+	})
+	return n
+}
+
+// parent returns the container of the element or nil for the root or an unknown element
+func (n *navigation) parent(d Discriminator) Discriminator {
+	if n == nil {
+		return nil
+	}
+	key, ok := identity(d)
+	if !ok {
+		return nil
+	}
+	return n.entries[key].parent
+}
+
+// siblingIndex returns the index of the element within the children of its parent or -1
+func (n *navigation) siblingIndex(d Discriminator) int {
+	if n == nil {
+		return -1
+	}
+	key, ok := identity(d)
+	if !ok {
+		return -1
+	}
+	if e, ok := n.entries[key]; ok {
+		return e.index
+	}
+	return -1
+}
+
+// breadcrumb returns the workspace, documents and chapters from the root down to the element, which is included
+// if it is one of them.
+func (n *navigation) breadcrumb(d Discriminator) []Discriminator {
+	var res []Discriminator
+	for ; d != nil; d = n.parent(d) {
+		switch d.(type) {
+		case *Workspace, *Document, *Chapter:
+			res = append([]Discriminator{d}, res...)
+		}
+	}
+	return res
+}
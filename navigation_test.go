@@ -0,0 +1,36 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"testing"
+)
+
+func TestBreadcrumb(t *testing.T) {
+	doc := &Document{Title: "doc"}
+	doc.NewChapter("first")
+	chap := doc.NewChapter("second")
+	chap.Text("text")
+	chap.NewChapter("sub")
+
+	tpl := `{{with index .Body 1}}{{with index .Body 1}}` +
+		`{{range breadcrumb .}}{{.Title}}/{{end}} {{siblingIndex .}} {{(parent .).Title}} {{parent (parent (parent .))}}` +
+		`{{end}}{{end}}`
+	if got := renderText(t, tpl, doc); got != "doc/second/sub/ 1 second <nil>" {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// NumberChapters calculates the Number of all chapters by their nesting, like 1, 1.1 and 1.2. Chapters after an
+// Appendix marker are numbered by letters instead, like A, A.1 and B. Unnumbered chapters and their children do not
+// consume a number, just like chapters deeper than the NumberDepth.
+func (c *Document) NumberChapters() {
+	numberChapters(c.Body, nil, &chapterCounter{depth: c.NumberDepth})
+}
+
+// NumberAll numbers the chapters and floats of all documents of the workspace continuously, in resource order,
+// like a single book. The first chapter of the second document continues the numbers of the first document and so
+// does its first figure or listing. Use Document.NumberChapters and Document.NumberFloats to number each document
+// on its own.
+func (w *Workspace) NumberAll() {
+	chapters := &chapterCounter{}
+	floats := &floatCounter{}
+	for _, res := range w.Resources {
+		doc, ok := res.(*Document)
+		if !ok {
+			continue
+		}
+		chapters.depth = doc.NumberDepth
+		numberChapters(doc.Body, nil, chapters)
+		numberFloats(doc, floats)
+	}
+}
+
+// chapterCounter is the state of the top level chapter numbering
+type chapterCounter struct {
+	count    int
+	appendix bool
+	parts    int
+	depth    int // depth is the deepest numbered level or 0 to number all levels, see Document.NumberDepth
+}
+
+// floatCounter is the state of the figure, table and listing numbering
+type floatCounter struct {
+	figures  int
+	tables   int
+	listings int
+}
+
+// NumberChapters numbers all chapters within the body, e.g. of a subtree. A standalone subtree uses an empty parent
+// and starts with 1. A transcluded subtree continues the numbering of its parent chapter, e.g. a parent of 2.3
+// results in 2.3.1, 2.3.2 and so on.
+func NumberChapters(body []Discriminator, parent string) {
+	var path []string
+	if parent != "" {
+		path = strings.Split(parent, ".")
+	}
+	numberChapters(body, path, &chapterCounter{})
+}
+
+// A TOCEntry describes a chapter or a part within the table of contents.
+type TOCEntry struct {
+	Number  string   // Number as calculated by NumberChapters
+	Title   string   // Title of the chapter
+	Level   int      // Level is the nesting depth, starting at the base level
+	Chapter *Chapter // Chapter is the actual element, unless it is a part
+	Part    *Part    // Part is only set for part entries, whose chapters are nested one level deeper
+}
+
+// TableOfContents returns all chapters in document order, starting at level 0.
+func (c *Document) TableOfContents() []TOCEntry {
+	return TableOfContents(c.Body, 0)
+}
+
+// TableOfContents returns all chapters within the body in document order. The top most chapters have the base
+// level, so a transcluded subtree can continue the levels of its parent. Chapters with HideFromTOC are skipped
+// together with their sub chapters.
+func TableOfContents(body []Discriminator, baseLevel int) []TOCEntry {
+	var res []TOCEntry
+	for _, e := range body {
+		if part, ok := e.(*Part); ok {
+			res = append(res, TOCEntry{Number: part.Number, Title: part.Title, Level: baseLevel, Part: part})
+			res = append(res, TableOfContents(part.Body, baseLevel+1)...)
+			continue
+		}
+		chap, ok := e.(*Chapter)
+		if !ok || chap.HideFromTOC {
+			continue
+		}
+		res = append(res, TOCEntry{Number: chap.Number, Title: chap.Title, Level: baseLevel, Chapter: chap})
+		res = append(res, TableOfContents(chap.Body, baseLevel+1)...)
+	}
+	return res
+}
+
+// numberChapters numbers all chapters of the body, which are prefixed by the parents numbers. Unnumbered chapters
+// and all of their children get no number. The top level continues the counter, the sub levels start at 1.
+func numberChapters(body []Discriminator, parent []string, top *chapterCounter) {
+	counter := top
+	if parent != nil {
+		counter = &chapterCounter{}
+	}
+	if top.depth > 0 && len(parent) > top.depth {
+		clearNumbers(body)
+		return
+	}
+	for _, e := range body {
+		if is(e, AppendixType) && parent == nil {
+			counter.appendix = true
+			counter.count = 0
+			continue
+		}
+		if part, ok := e.(*Part); ok && parent == nil {
+			counter.parts++
+			part.Number = roman(counter.parts)
+			numberChapters(part.Body, nil, counter)
+			continue
+		}
+		chap, ok := e.(*Chapter)
+		if !ok {
+			continue
+		}
+		if chap.Unnumbered {
+			clearNumbers([]Discriminator{chap})
+			continue
+		}
+		counter.count++
+		num := strconv.Itoa(counter.count)
+		if counter.appendix {
+			num = letters(counter.count)
+		}
+		path := append(append([]string{}, parent...), num)
+		chap.Number = strings.Join(path, ".")
+		numberChapters(chap.Body, path, top)
+	}
+}
+
+// Outline returns a lightweight copy of the document, which only contains the chapter hierarchy. The chapters keep
+// their titles, levels and numbers, but their bodies only contain their sub chapters.
+func (c *Document) Outline() *Document {
+	return &Document{Id: c.Id, Title: c.Title, Body: outline(c.Body)}
+}
+
+func outline(body []Discriminator) []Discriminator {
+	var res []Discriminator
+	for _, e := range body {
+		if part, ok := e.(*Part); ok {
+			res = append(res, &Part{Title: part.Title, Number: part.Number, Body: outline(part.Body)})
+			continue
+		}
+		if chap, ok := e.(*Chapter); ok {
+			res = append(res, &Chapter{
+				Title:       chap.Title,
+				Level:       chap.Level,
+				Number:      chap.Number,
+				Unnumbered:  chap.Unnumbered,
+				HideFromTOC: chap.HideFromTOC,
+				Body:        outline(chap.Body),
+			})
+		}
+	}
+	return res
+}
+
+// NumberFloats assigns sequential numbers to all figures (not inline images or covers), tables and listings (only
+// captioned ones) in document order, starting at 1. Each kind of float has its own counter.
+func (c *Document) NumberFloats() {
+	numberFloats(c, &floatCounter{})
+}
+
+// numberFloats numbers the floats of the tree, continuing the given counter
+func numberFloats(root Discriminator, counter *floatCounter) {
+	var cover *Image
+	Walk(root, func(d Discriminator) bool {
+		switch t := d.(type) {
+		case *Titlepage:
+			cover = t.Cover
+		case *Image:
+			t.Number = 0
+			if !t.Inline && t != cover {
+				counter.figures++
+				t.Number = counter.figures
+			}
+		case *Table:
+			t.Number = 0
+			if t.Caption != "" {
+				counter.tables++
+				t.Number = counter.tables
+			}
+		case *Code:
+			t.Number = 0
+			if t.Caption != "" {
+				counter.listings++
+				t.Number = counter.listings
+			}
+		}
+		return true
+	})
+}
+
+// floatNumber returns the number of a float as calculated by Document.NumberFloats or 0.
+func floatNumber(d Discriminator) int {
+	switch t := d.(type) {
+	case *Image:
+		return t.Number
+	case *Table:
+		return t.Number
+	case *Code:
+		return t.Number
+	default:
+		return 0
+	}
+}
+
+// clearNumbers removes the numbers of all chapters within the body
+func clearNumbers(body []Discriminator) {
+	for _, e := range body {
+		if part, ok := e.(*Part); ok {
+			part.Number = ""
+			clearNumbers(part.Body)
+		}
+		if chap, ok := e.(*Chapter); ok {
+			chap.Number = ""
+			clearNumbers(chap.Body)
+		}
+	}
+}
+
+// letters converts 1, 2, ..., 26, 27 into A, B, ..., Z, AA
+func letters(n int) string {
+	var res []byte
+	for n > 0 {
+		n--
+		res = append([]byte{byte('A' + n%26)}, res...)
+		n /= 26
+	}
+	return string(res)
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chapterNumbers returns the titles and numbers of all chapters in pre-order.
+func chapterNumbers(body []Discriminator) []string {
+	var res []string
+	for _, e := range body {
+		if chap, ok := e.(*Chapter); ok {
+			res = append(res, chap.Title+"="+chap.Number)
+			res = append(res, chapterNumbers(chap.Body)...)
+		}
+	}
+	return res
+}
+
+func TestNumberChapters(t *testing.T) {
+	doc := createModel(t).ById("1234").(*Document)
+	doc.NumberChapters()
+	want := []string{"my first chapter=1", "a section=1.1", "a subsection=1.1.1", "another main chapter=2"}
+	if got := chapterNumbers(doc.Body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+}
+
+func TestAppendixNumbering(t *testing.T) {
+	doc := &Document{}
+	doc.NewChapter("intro")
+	doc.Add(Appendix())
+	doc.NewChapter("glossary").NewChapter("terms")
+	doc.NewChapter("index")
+
+	doc = roundTrip(t, doc).(*Document)
+	if !is(doc.Body[1], AppendixType) {
+		t.Fatalf("expected appendix marker but got %s", doc.Body[1].Type())
+	}
+
+	doc.NumberChapters()
+	want := []string{"intro=1", "glossary=A", "terms=A.1", "index=B"}
+	if got := chapterNumbers(doc.Body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+}
+
+func TestLetters(t *testing.T) {
+	for n, want := range map[int]string{1: "A", 2: "B", 26: "Z", 27: "AA", 52: "AZ", 53: "BA"} {
+		if got := letters(n); got != want {
+			t.Fatalf("%d: expected %s but got %s", n, want, got)
+		}
+	}
+}
+
+func tocOf(entries []TOCEntry) []string {
+	var res []string
+	for _, e := range entries {
+		res = append(res, fmt.Sprintf("%d:%s=%s", e.Level, e.Title, e.Number))
+	}
+	return res
+}
+
+func TestNumberSubtree(t *testing.T) {
+	chap := &Chapter{Title: "root"}
+	chap.NewChapter("a").NewChapter("a1")
+	chap.NewChapter("b")
+
+	NumberChapters(chap.Body, "")
+	want := []string{"0:a=1", "1:a1=1.1", "0:b=2"}
+	if got := tocOf(TableOfContents(chap.Body, 0)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+
+	NumberChapters(chap.Body, "2.3")
+	want = []string{"2:a=2.3.1", "3:a1=2.3.1.1", "2:b=2.3.2"}
+	if got := tocOf(TableOfContents(chap.Body, 2)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+}
+
+func TestDocumentTableOfContents(t *testing.T) {
+	doc := createModel(t).ById("1234").(*Document)
+	doc.NumberChapters()
+	want := []string{"0:my first chapter=1", "1:a section=1.1", "2:a subsection=1.1.1", "0:another main chapter=2"}
+	if got := tocOf(doc.TableOfContents()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+}
+
+func TestUnnumbered(t *testing.T) {
+	doc := &Document{}
+	preface := doc.NewChapter("preface")
+	preface.Unnumbered = true
+	preface.NewChapter("thanks")
+	doc.NewChapter("intro").NewChapter("goals")
+	doc.NewChapter("usage")
+
+	doc = roundTrip(t, doc).(*Document)
+	if !doc.Body[0].(*Chapter).Unnumbered || doc.Body[1].(*Chapter).Unnumbered {
+		t.Fatal("unnumbered flag not preserved")
+	}
+
+	doc.NumberChapters()
+	want := []string{"preface=", "thanks=", "intro=1", "goals=1.1", "usage=2"}
+	if got := chapterNumbers(doc.Body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+}
+
+func TestNumberFloats(t *testing.T) {
+	doc := &Document{}
+	doc.Add(&Image{Src: "a.png"})
+	doc.NewChapter("chap").Add(Text("text"), Bold(&Image{Src: "b.png"}))
+	doc.NumberFloats()
+
+	got := renderText(t, `{{range .Collect}}Figure {{floatNumber .}}: {{.Src}};{{end}}`, struct{ Collect []Discriminator }{Collect(doc, ImageType)})
+	if got != "Figure 1: a.png;Figure 2: b.png;" {
+		t.Fatal(got)
+	}
+}
+
+func TestNumberListings(t *testing.T) {
+	doc := &Document{}
+	doc.Add(&Code{Id: "first", Caption: "first", Lines: []string{"a"}}, &Code{Lines: []string{"uncaptioned"}})
+	doc.NewChapter("chap").Add(&Image{Src: "a.png"}, &Code{Id: "second", Caption: "second", Lines: []string{"b"}})
+	doc.NumberFloats()
+
+	got := renderText(t, `{{range .Collect}}{{if .Caption}}Listing {{floatNumber .}}: {{.Caption}};{{end}}{{end}}`, struct{ Collect []Discriminator }{Collect(doc, CodeType)})
+	if got != "Listing 1: first;Listing 2: second;" {
+		t.Fatal(got)
+	}
+	if img := Collect(doc, ImageType)[0].(*Image); img.Number != 1 {
+		t.Fatalf("figures must have their own counter, got %d", img.Number)
+	}
+
+	res := roundTrip(t, doc.Body[0]).(*Code)
+	if res.Id != "first" || res.Caption != "first" {
+		t.Fatalf("unexpected %+v", res)
+	}
+	html := renderHTML(doc.Body[0])
+	if !strings.Contains(html, `<figure class="listing" id="first">`) || !strings.Contains(html, "<figcaption>Listing 1: first</figcaption>") {
+		t.Fatal(html)
+	}
+}
+
+func TestOutline(t *testing.T) {
+	doc := createModel(t).ById("1234").(*Document)
+	out := doc.Outline()
+	if got, want := titlesOf(Collect(out, ChapterType)), titlesOf(Collect(doc, ChapterType)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	if spans := Collect(out, TextType); len(spans) != 0 {
+		t.Fatalf("expected no spans but got %d", len(spans))
+	}
+	if sub := out.Body[0].(*Chapter).Body[0].(*Chapter); sub.Title != "a section" || sub.Level != 1 {
+		t.Fatalf("nesting not preserved: %+v", sub)
+	}
+	if len(Collect(doc, TextType)) == 0 {
+		t.Fatal("original document must not be modified")
+	}
+}
+
+func TestInlineImages(t *testing.T) {
+	icon := &Image{Src: "warning.svg", Inline: true}
+	if res := roundTrip(t, icon); !reflect.DeepEqual(icon, res) {
+		t.Fatalf("expected %+v but got %+v", icon, res)
+	}
+
+	doc := &Document{}
+	figure := &Image{Src: "a.png"}
+	doc.NewChapter("chap").Add(icon, Text(" careful"), figure)
+	doc.NumberFloats()
+	if icon.Number != 0 || figure.Number != 1 {
+		t.Fatalf("inline images must not be numbered: %d %d", icon.Number, figure.Number)
+	}
+	want := "<section>\n<h2>chap</h2>\n<p><img src=\"warning.svg\"> careful</p>\n" +
+		"<figure><img src=\"a.png\"><figcaption>Figure 1</figcaption></figure>\n</section>\n"
+	if got := renderHTML(doc.Body[0]); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+	if got := renderLatex(icon); got != "\\raisebox{-0.2\\height}{\\includegraphics[height=1em]{warning.svg}}" {
+		t.Fatal(got)
+	}
+}
+
+func TestHideFromTOC(t *testing.T) {
+	doc := &Document{}
+	doc.Add(TOC())
+	doc.NewChapter("intro")
+	figures := doc.NewChapter("figures")
+	figures.HideFromTOC = true
+	figures.NewChapter("a figure")
+	doc.NewChapter("usage")
+
+	doc = roundTrip(t, doc).(*Document)
+	if !doc.Body[2].(*Chapter).HideFromTOC {
+		t.Fatal("hidden flag not preserved")
+	}
+
+	doc.NumberChapters()
+	want := []string{"0:intro=1", "0:usage=3"}
+	if got := tocOf(doc.TableOfContents()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	html := renderHTML(doc)
+	toc := html[:strings.Index(html, "</nav>")]
+	if strings.Contains(toc, "figures") || !strings.Contains(html, "<h2>2 figures</h2>") {
+		t.Fatal(html)
+	}
+
+	figures = doc.Body[2].(*Chapter)
+	figures.Body[0].(*Chapter).HideFromTOC = true
+	latex := renderLatex(doc)
+	if strings.Count(latex, "{tocdepth}{-2}") != 1 || strings.Count(latex, "{tocdepth}{\\arabic{tocdepth}}") != 1 ||
+		strings.Index(latex, "{tocdepth}{\\arabic{tocdepth}}") < strings.Index(latex, "a figure") {
+		t.Fatalf("expected a single suppression around the nested hidden chapters but got\n%s", latex)
+	}
+}
+
+func TestNumberAll(t *testing.T) {
+	ws := &Workspace{}
+	first := ws.NewDocument()
+	first.NewChapter("intro").NewChapter("goals")
+	first.NewChapter("usage").Add(&Image{Src: "a.png"})
+	second := ws.NewDocument()
+	second.NewChapter("api").Add(&Image{Src: "b.png"}, &Code{Caption: "main"})
+	second.NewChapter("faq")
+
+	ws.NumberAll()
+	want := []string{"intro=1", "goals=1.1", "usage=2"}
+	if got := chapterNumbers(first.Body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	want = []string{"api=3", "faq=4"}
+	if got := chapterNumbers(second.Body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	if img := Collect(second, ImageType)[0].(*Image); img.Number != 2 {
+		t.Fatalf("expected figure 2 but got %d", img.Number)
+	}
+
+	second.NumberChapters()
+	second.NumberFloats()
+	if got := chapterNumbers(second.Body); got[0] != "api=1" {
+		t.Fatalf("expected per document numbering but got %v", got)
+	}
+}
+
+func TestNumberDepth(t *testing.T) {
+	doc := &Document{NumberDepth: 2}
+	doc.NewChapter("intro").NewChapter("goals").NewChapter("scope").NewChapter("details").NewChapter("more")
+	doc.NewChapter("usage")
+
+	doc = roundTrip(t, doc).(*Document)
+	if doc.NumberDepth != 2 {
+		t.Fatal("number depth not preserved")
+	}
+
+	doc.NumberChapters()
+	want := []string{"0:intro=1", "1:goals=1.1", "2:scope=1.1.1", "3:details=", "4:more=", "0:usage=2"}
+	if got := tocOf(doc.TableOfContents()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	if latex := renderLatex(doc); !strings.HasPrefix(latex, "\\setcounter{secnumdepth}{2}\n") {
+		t.Fatal(latex)
+	}
+
+	doc.NumberDepth = 0
+	doc.NumberChapters()
+	if got := chapterNumbers(doc.Body); got[3] != "details=1.1.1.1" {
+		t.Fatalf("expected all levels to be numbered but got %v", got)
+	}
+}
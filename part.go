@@ -0,0 +1,89 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import "strings"
+
+// A Part groups chapters of a large book, like Part I and Part II, and usually begins with a divider page. It is
+// placed into the body of a Document and contains top level chapters. The chapter numbers continue across parts.
+type Part struct {
+	Title  string
+	Number string // Number is not serialized but calculated by Document.NumberChapters, e.g. II
+	Body   []Discriminator
+}
+
+// NewPart creates a new part with the given title
+func NewPart(title string) *Part {
+	return &Part{Title: title}
+}
+
+func (p *Part) Add(e ...Discriminator) *Part {
+	p.Body = append(p.Body, e...)
+	return p
+}
+
+// NewChapter appends a new top level chapter
+func (p *Part) NewChapter(title string) *Chapter {
+	chap := &Chapter{Title: title}
+	p.Body = append(p.Body, chap)
+	return chap
+}
+
+func (p *Part) Type() string {
+	return PartType
+}
+
+func (p *Part) children() []Discriminator {
+	return p.Body
+}
+
+func (p *Part) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = p.Type()
+	m["title"] = p.Title
+	m["body"] = toJson(p.Body)
+	return m
+}
+
+func (p *Part) fromJson(m map[string]interface{}) {
+	p.Title = optString(m, "title")
+	p.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		p.Body = append(p.Body, fromJson(obj))
+	}
+}
+
+// romanNumerals are the values and symbols of roman numbers, including the subtractive forms
+var romanNumerals = []struct {
+	value  int
+	symbol string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+	{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+// roman converts 1, 2, ..., 4, ..., 9 into I, II, ..., IV, ..., IX
+func roman(n int) string {
+	sb := &strings.Builder{}
+	for _, r := range romanNumerals {
+		for n >= r.value {
+			sb.WriteString(r.symbol)
+			n -= r.value
+		}
+	}
+	return sb.String()
+}
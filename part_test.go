@@ -0,0 +1,58 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPartRoundTrip(t *testing.T) {
+	part := NewPart("Basics")
+	part.NewChapter("intro").Add(Text("hello"))
+	got := roundTrip(t, part).(*Part)
+	if !reflect.DeepEqual(part, got) {
+		t.Fatalf("expected %+v but got %+v", part, got)
+	}
+}
+
+func TestPartNumbering(t *testing.T) {
+	doc := &Document{}
+	doc.NewChapter("preface").Unnumbered = true
+	basics := NewPart("Basics")
+	basics.NewChapter("intro").NewChapter("goals")
+	basics.NewChapter("setup")
+	advanced := NewPart("Advanced")
+	advanced.NewChapter("tuning")
+	doc.Add(basics, advanced)
+
+	doc.NumberChapters()
+	if basics.Number != "I" || advanced.Number != "II" {
+		t.Fatalf("expected I and II but got %s and %s", basics.Number, advanced.Number)
+	}
+	want := []string{"0:preface=", "0:Basics=I", "1:intro=1", "2:goals=1.1", "1:setup=2", "0:Advanced=II", "1:tuning=3"}
+	if got := tocOf(doc.TableOfContents()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	if got := renderLatex(advanced); !strings.HasPrefix(got, "\\part{Advanced}\n\n\\chapter{tuning}") {
+		t.Fatal(got)
+	}
+	if got := roman(1994); got != "MCMXCIV" {
+		t.Fatal(got)
+	}
+}
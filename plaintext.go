@@ -0,0 +1,167 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// PreserveWhitespace returns true for elements whose whitespace is significant, like Code or a Diff. The whitespace of any
+// other text, like a Span, is collapsed by convention.
+func PreserveWhitespace(d Discriminator) bool {
+	switch d.(type) {
+	case *Code, *Diff:
+		return true
+	default:
+		return false
+	}
+}
+
+// PlainText exports the text of the element and all of its children. Each whitespace sequence of a Span is
+// collapsed into a single space, but Code is exported line by line, as is.
+func PlainText(d Discriminator) string {
+	sb := &strings.Builder{}
+	writePlainText(sb, d, true)
+	return sb.String()
+}
+
+// writePlainText appends the text of the element and its children. Code and diffs are skipped, unless code is set.
+func writePlainText(sb *strings.Builder, d Discriminator, code bool) {
+	if !code && (is(d, CodeType) || is(d, DiffType)) {
+		return
+	}
+	switch t := d.(type) {
+	case *Span:
+		sb.WriteString(collapseWhitespace(t.Value))
+	case *VarRef:
+		sb.WriteString(t.Value)
+	case *RevisionHistory:
+		for _, rev := range t.Entries {
+			sb.WriteString(strings.Join([]string{rev.Version, rev.Date, rev.Author, rev.Summary}, " "))
+			sb.WriteString("\n")
+		}
+	case *Table:
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString("\n")
+		}
+		writePlainTable(sb, t, code)
+	case *Hyperlink:
+		if len(t.Body) == 0 {
+			sb.WriteString(t.Href)
+		}
+		for _, c := range t.Body {
+			writePlainText(sb, c, code)
+		}
+	case *Abbreviation:
+		sb.WriteString(t.Text())
+	case *Embed:
+		sb.WriteString(t.URL)
+	case *Attachment:
+		sb.WriteString(t.DisplayLabel())
+	case *Code:
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString("\n")
+		}
+		for _, line := range t.Lines {
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+	case *Diff:
+		if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+			sb.WriteString("\n")
+		}
+		for _, line := range t.Lines {
+			sb.WriteString(line.Prefix())
+			sb.WriteString(line.Text)
+			sb.WriteString("\n")
+		}
+	case container:
+		for _, c := range t.children() {
+			writePlainText(sb, c, code)
+		}
+	default:
+		switch d.Type() {
+		case LineBreakType:
+			sb.WriteString("\n")
+		case ParagraphBreakType:
+			sb.WriteString("\n\n")
+		}
+	}
+}
+
+// collapseWhitespace replaces each sequence of whitespace by a single space
+func collapseWhitespace(str string) string {
+	sb := &strings.Builder{}
+	space := false
+	for _, r := range str {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			sb.WriteRune(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	if space {
+		sb.WriteRune(' ')
+	}
+	return sb.String()
+}
+
+// writePlainTable appends the rows of the table as lines, whose columns are aligned by their display width. The
+// header is separated by a line of dashes.
+func writePlainTable(sb *strings.Builder, t *Table, code bool) {
+	rows := t.AllRows()
+	cells := make([][]string, len(rows))
+	widths := make([]int, t.Columns())
+	for i, row := range rows {
+		for j, cell := range row.Cells {
+			cellSb := &strings.Builder{}
+			for _, e := range cell.Body {
+				writePlainText(cellSb, e, code)
+			}
+			text := strings.TrimSpace(collapseWhitespace(cellSb.String()))
+			cells[i] = append(cells[i], text)
+			if w := DisplayWidth(text); w > widths[j] {
+				widths[j] = w
+			}
+		}
+	}
+	for i := range rows {
+		line := make([]string, len(widths))
+		for j := range widths {
+			text := ""
+			if j < len(cells[i]) {
+				text = cells[i][j]
+			}
+			line[j] = alignText(text, widths[j], t.ColumnAlign(j))
+		}
+		sb.WriteString(strings.TrimRight(strings.Join(line, " | "), " "))
+		sb.WriteString("\n")
+		if i == len(t.Header)-1 {
+			sep := make([]string, len(widths))
+			for j, w := range widths {
+				sep[j] = strings.Repeat("-", w)
+			}
+			sb.WriteString(strings.Join(sep, "-+-"))
+			sb.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"testing"
+)
+
+func TestPlainTextWhitespace(t *testing.T) {
+	chap := &Chapter{}
+	chap.Add(Text("  indented\n\t\tspan  text "), Bold(Text("bold")))
+	chap.Add(&Code{Hint: "go", Lines: []string{"func main() {", "\tfmt.Println(\"  x  \")", "}"}})
+	chap.Add(Text("after"))
+
+	want := " indented span text bold\nfunc main() {\n\tfmt.Println(\"  x  \")\n}\nafter"
+	if got := PlainText(chap); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+
+	if !PreserveWhitespace(&Code{}) || PreserveWhitespace(Text("x")) || PreserveWhitespace(chap) {
+		t.Fatal("only code must preserve whitespace")
+	}
+
+	got := renderText(t, `{{range .Body}}{{if preserveWhitespace .}}code{{else}}text{{end}},{{end}}`, chap)
+	if got != "text,text,code,text," {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+)
+
+// A Requirement contains the fragments, which an element type requires in the enclosing output format.
+type Requirement struct {
+	Preamble string // Preamble is a Latex fragment like \usepackage{listings}
+	CSS      string // CSS contains the style definitions for html outputs
+}
+
+// requirements of each element type by type name
+var requirements = map[string]Requirement{
+	AbbreviationType: {Preamble: `\usepackage{acronym}`},
+	AttachmentType:   {Preamble: `\usepackage{attachfile}`},
+	CodeType:         {Preamble: `\usepackage{listings}`},
+	DiffType: {
+		Preamble: "\\usepackage{listings}\n\\usepackage[table]{xcolor}",
+		CSS: ".diff-add { background-color: #e6ffed; }\n" +
+			".diff-del { background-color: #ffeef0; }\n" +
+			".diff-context { color: #6a737d; }",
+	},
+	ImageType: {Preamble: `\usepackage{graphicx}`},
+	LinkType:  {Preamble: `\usepackage{hyperref}`},
+	ListType:  {Preamble: `\usepackage{enumitem}`},
+	MarginNoteType: {
+		CSS: ".marginnote { float: right; clear: right; width: 30%; margin-right: -35%; font-size: 0.8em; }",
+	},
+	TitlepageType: {Preamble: `\usepackage{tikz}`},
+	TooltipType:   {CSS: ".tooltip { border-bottom: 1px dotted; cursor: help; }"},
+	UnderlineType: {Preamble: `\usepackage[normalem]{ulem}`},
+}
+
+// RegisterRequirement declares the preamble and css fragments, which are needed to typeset elements of the given
+// type. An existing declaration is replaced. Registration is not thread safe and should happen at initialization.
+func RegisterRequirement(typeName string, r Requirement) {
+	requirements[typeName] = r
+}
+
+// RequiredPreamble returns the Latex preamble fragments of all element types, which are actually present in the
+// tree. Each line is only included once, in the order of first appearance.
+func RequiredPreamble(d Discriminator) string {
+	return collectRequirements(d, func(r Requirement) string {
+		return r.Preamble
+	})
+}
+
+// RequiredCSS returns the css fragments of all element types, which are actually present in the tree. Each line is
+// only included once, in the order of first appearance.
+func RequiredCSS(d Discriminator) string {
+	return collectRequirements(d, func(r Requirement) string {
+		return r.CSS
+	})
+}
+
+// landscapeRequirement is needed by landscape chapters only, so it cannot be declared by type
+var landscapeRequirement = Requirement{
+	Preamble: `\usepackage{pdflscape}`,
+	CSS:      "@page landscape { size: landscape; }\n.landscape { page: landscape; }",
+}
+
+// justifyRequirement is needed by justified alignments only, so it cannot be declared by type
+var justifyRequirement = Requirement{Preamble: `\usepackage{ragged2e}`}
+
+func collectRequirements(d Discriminator, fragment func(r Requirement) string) string {
+	var lines []string
+	seen := make(map[string]bool)
+	add := func(r Requirement) {
+		for _, line := range strings.Split(fragment(r), "\n") {
+			if strings.TrimSpace(line) == "" || seen[line] {
+				continue
+			}
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	Walk(d, func(d Discriminator) bool {
+		if chap, ok := d.(*Chapter); ok && chap.IsLandscape() {
+			add(landscapeRequirement)
+		}
+		if align, ok := d.(*Align); ok && align.Alignment == AlignJustify {
+			add(justifyRequirement)
+		}
+		if r, ok := requirements[d.Type()]; ok {
+			add(r)
+		}
+		if a, ok := d.(*Abbreviation); ok && a.Long != "" {
+			add(Requirement{Preamble: latexAcronym(a)})
+		}
+		return true
+	})
+	return strings.Join(lines, "\n")
+}
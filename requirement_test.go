@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"testing"
+)
+
+func TestRequiredPreamble(t *testing.T) {
+	doc := &Document{}
+	chap := doc.NewChapter("code")
+	chap.Add(&Code{Hint: "go"}, Text("x"), &Code{Hint: "java"})
+
+	if got := RequiredPreamble(doc); got != `\usepackage{listings}` {
+		t.Fatalf("unexpected preamble %q", got)
+	}
+	if got := renderText(t, `{{requiredPreamble .}}`, doc); got != `\usepackage{listings}` {
+		t.Fatalf("unexpected template preamble %q", got)
+	}
+	if got := RequiredPreamble(&Document{Body: []Discriminator{Text("no code")}}); got != "" {
+		t.Fatalf("expected no preamble but got %q", got)
+	}
+}
+
+func TestRegisterRequirement(t *testing.T) {
+	const colorType = "test-color"
+	RegisterRequirement(colorType, Requirement{Preamble: "\\usepackage{xcolor}\n\\usepackage{listings}", CSS: ".c{}"})
+	defer delete(requirements, colorType)
+
+	doc := &Document{Body: []Discriminator{&Code{}, &defaultBody{name: colorType}}}
+	if got := RequiredPreamble(doc); got != "\\usepackage{listings}\n\\usepackage{xcolor}" {
+		t.Fatalf("unexpected preamble %q", got)
+	}
+	if got := RequiredCSS(doc); got != ".c{}" {
+		t.Fatalf("unexpected css %q", got)
+	}
+}
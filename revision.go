@@ -0,0 +1,74 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// A RevisionHistory lists the versions of a controlled document. Renderers typeset it as a table on a dedicated
+// page.
+type RevisionHistory struct {
+	Entries []*Revision
+}
+
+// A Revision is a single entry of a RevisionHistory
+type Revision struct {
+	Version string
+	Date    string // Date is free text, like 2020-03-01
+	Author  string
+	Summary string // Summary of the changes
+}
+
+// NewRevisionHistory creates an empty revision history
+func NewRevisionHistory() *RevisionHistory {
+	return &RevisionHistory{}
+}
+
+// Add appends the revisions
+func (h *RevisionHistory) Add(rev ...*Revision) *RevisionHistory {
+	h.Entries = append(h.Entries, rev...)
+	return h
+}
+
+func (h *RevisionHistory) Type() string {
+	return RevisionHistoryType
+}
+
+func (h *RevisionHistory) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = h.Type()
+	var entries []interface{}
+	for _, rev := range h.Entries {
+		entry := make(map[string]interface{})
+		entry["version"] = rev.Version
+		optSet(entry, "date", rev.Date)
+		optSet(entry, "author", rev.Author)
+		optSet(entry, "summary", rev.Summary)
+		entries = append(entries, entry)
+	}
+	m["entries"] = entries
+	return m
+}
+
+func (h *RevisionHistory) fromJson(m map[string]interface{}) {
+	h.Entries = nil
+	for _, obj := range assertObjList(m["entries"]) {
+		h.Entries = append(h.Entries, &Revision{
+			Version: optString(obj, "version"),
+			Date:    optString(obj, "date"),
+			Author:  optString(obj, "author"),
+			Summary: optString(obj, "summary"),
+		})
+	}
+}
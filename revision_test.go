@@ -0,0 +1,40 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRevisionHistory(t *testing.T) {
+	history := NewRevisionHistory().Add(
+		&Revision{Version: "1.0", Date: "2020-03-01", Author: "Torben", Summary: "initial release"},
+		&Revision{Version: "1.1", Date: "2020-04-15", Summary: "fixed typos"},
+	)
+	got := roundTrip(t, &Document{Body: []Discriminator{history}}).(*Document).Body[0]
+	if !reflect.DeepEqual(history, got) {
+		t.Fatalf("expected %+v but got %+v", history, got)
+	}
+	if html := renderHTML(history); !strings.Contains(html, "<tr><td>1.1</td><td>2020-04-15</td><td></td><td>fixed typos</td></tr>") {
+		t.Fatal(html)
+	}
+	if latex := renderLatex(history); !strings.Contains(latex, "1.0 & 2020-03-01 & Torben & initial release \\\\\n") {
+		t.Fatal(latex)
+	}
+}
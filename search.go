@@ -0,0 +1,47 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+)
+
+// A SearchDoc is the representation of a document for a search engine.
+type SearchDoc struct {
+	Id       string
+	Title    string
+	Authors  []string // Authors contains the full names of the authors
+	Keywords []string // Keywords are the titles of all chapters
+	Body     string   // Body is the plain text of the document without any code
+}
+
+// SearchDocument extracts the text and metadata of the document for indexing.
+func (c *Document) SearchDocument() SearchDoc {
+	doc := SearchDoc{Id: c.Id, Title: c.Title}
+	for _, a := range c.Authors {
+		if name := strings.TrimSpace(a.Firstname + " " + a.Lastname); name != "" {
+			doc.Authors = append(doc.Authors, name)
+		}
+	}
+	for _, chap := range Collect(c, ChapterType) {
+		doc.Keywords = append(doc.Keywords, chap.(*Chapter).Title)
+	}
+	sb := &strings.Builder{}
+	writePlainText(sb, c, false)
+	doc.Body = strings.TrimSpace(sb.String())
+	return doc
+}
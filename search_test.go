@@ -0,0 +1,44 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchDocument(t *testing.T) {
+	doc := &Document{Id: "doc", Title: "Manual", Authors: []*Author{{Firstname: "Ada", Lastname: "Lovelace"}}}
+	chap := doc.NewChapter("Install")
+	chap.Add(Text("Download the installer."), &Code{Hint: "sh", Lines: []string{"curl secret-command"}})
+	chap.NewChapter("Configure").Add(Text("Edit the config."), NewDiff("go").AddLine("diff-content"))
+
+	res := doc.SearchDocument()
+	if res.Title != "Manual" || !reflect.DeepEqual(res.Authors, []string{"Ada Lovelace"}) {
+		t.Fatalf("unexpected metadata %+v", res)
+	}
+	if !reflect.DeepEqual(res.Keywords, []string{"Install", "Configure"}) {
+		t.Fatalf("unexpected keywords %v", res.Keywords)
+	}
+	if !strings.Contains(res.Body, "Download the installer.") || !strings.Contains(res.Body, "Edit the config.") {
+		t.Fatalf("missing chapter text in %q", res.Body)
+	}
+	if strings.Contains(res.Body, "secret-command") || strings.Contains(res.Body, "diff-content") {
+		t.Fatalf("code must be excluded from %q", res.Body)
+	}
+}
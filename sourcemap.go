@@ -0,0 +1,107 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// sourceMapSuffix is appended to the name of a generated file to name its source map, see Template.EmitSourceMap
+const sourceMapSuffix = ".map.json"
+
+// A sourceSpan is the byte range of the output of an element within the output of a renderer
+type sourceSpan struct {
+	d          Discriminator
+	start, end int
+}
+
+// A sourceFragment is the output of a renderer template function, like renderHTML
+type sourceFragment struct {
+	text  string
+	spans []sourceSpan
+}
+
+// A sourceMapEntry relates a range of a generated file to the element, which produced it
+type sourceMapEntry struct {
+	Type      string `json:"type"`
+	Path      string `json:"path"`
+	Start     int    `json:"start"`     // Start is the offset of the first byte
+	End       int    `json:"end"`       // End is the offset after the last byte
+	StartLine int    `json:"startLine"` // StartLine is the 1-based line of the first byte
+	EndLine   int    `json:"endLine"`   // EndLine is the 1-based line of the last byte
+}
+
+// recordFragment keeps the output of a renderer for the source map of the current file
+func (p *Template) recordFragment(text string, spans []sourceSpan) {
+	if p.EmitSourceMap {
+		p.fragments = append(p.fragments, sourceFragment{text: text, spans: spans})
+	}
+}
+
+// sourceMap locates the recorded fragments in order within the output and returns the entries of their elements,
+// outer elements first. A fragment, which has been altered by the template, cannot be found and is skipped.
+func (p *Template) sourceMap(out []byte) []sourceMapEntry {
+	var res []sourceMapEntry
+	offset := 0
+	for _, frag := range p.fragments {
+		idx := bytes.Index(out[offset:], []byte(frag.text))
+		if frag.text == "" || idx < 0 {
+			continue
+		}
+		base := offset + idx
+		for _, span := range frag.spans {
+			entry := sourceMapEntry{Type: span.d.Type(), Start: base + span.start, End: base + span.end}
+			if key, ok := identity(span.d); ok {
+				entry.Path = p.paths[key]
+			}
+			entry.StartLine = 1 + bytes.Count(out[:entry.Start], []byte("\n"))
+			entry.EndLine = entry.StartLine
+			if entry.End > entry.Start {
+				entry.EndLine = 1 + bytes.Count(out[:entry.End-1], []byte("\n"))
+			}
+			res = append(res, entry)
+		}
+		offset = base + len(frag.text)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Start != res[j].Start {
+			return res[i].Start < res[j].Start
+		}
+		return res[i].End > res[j].End
+	})
+	return res
+}
+
+// writeSourceMap writes the source map of the output next to the generated file, unless it is empty
+func (p *Template) writeSourceMap(dstFile string, out []byte) error {
+	entries := p.sourceMap(out)
+	if len(entries) == 0 {
+		return nil
+	}
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode source map of %s: %w", dstFile, err)
+	}
+	if err := ioutil.WriteFile(dstFile+sourceMapSuffix, b, p.fileMode()); err != nil {
+		return fmt.Errorf("unable to write source map of %s: %w", dstFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"time"
+)
+
+// The build phases which are measured by BuildStats.
+const (
+	PhaseProvide   = "provide"   // cloning, pulling or locating a template
+	PhaseParse     = "parse"     // reading and parsing the template files
+	PhaseRender    = "render"    // applying the model to the template files
+	PhaseAutobuild = "autobuild" // running an external build tool like latexmk
+	PhaseCopy      = "copy"      // copying the results into the output folder
+)
+
+// BuildStats accumulates the durations of each build phase, in total and per rule.
+type BuildStats struct {
+	Total  time.Duration                       // Total is the wall time of the entire build
+	Phases map[string]time.Duration            // Phases sums up the durations of all rules by phase
+	Rules  map[string]map[string]time.Duration // Rules contains the durations by phase for each BuildRule.Name
+}
+
+func newBuildStats() *BuildStats {
+	return &BuildStats{
+		Phases: make(map[string]time.Duration),
+		Rules:  make(map[string]map[string]time.Duration),
+	}
+}
+
+// add records the duration of a phase for the given rule name.
+func (s *BuildStats) add(rule string, phase string, d time.Duration) {
+	s.Phases[phase] += d
+	phases := s.Rules[rule]
+	if phases == nil {
+		phases = make(map[string]time.Duration)
+		s.Rules[rule] = phases
+	}
+	phases[phase] += d
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// A Table arranges cells in rows and columns. Header rows are typeset above the rows and repeated on each page, if
+// supported by the output.
+type Table struct {
+	Caption string // Caption is optional, only captioned tables are numbered
+	Number  int    // Number is not serialized but calculated by Document.NumberFloats, e.g. 2 for Table 2
+
+	// Placement is an optional latex float specifier made of h, t, b, p and !, e.g. htbp. Html ignores it.
+	Placement string
+
+	Header []*TableRow
+	Rows   []*TableRow
+}
+
+// NewTable creates an empty table
+func NewTable() *Table {
+	return &Table{}
+}
+
+// AddHeader appends a header row made of the cells
+func (t *Table) AddHeader(cells ...*TableCell) *Table {
+	t.Header = append(t.Header, &TableRow{Cells: cells})
+	return t
+}
+
+// AddRow appends a row made of the cells
+func (t *Table) AddRow(cells ...*TableCell) *Table {
+	t.Rows = append(t.Rows, &TableRow{Cells: cells})
+	return t
+}
+
+// Columns returns the amount of columns, which is the amount of cells of the longest row
+func (t *Table) Columns() int {
+	n := 0
+	for _, row := range t.AllRows() {
+		if len(row.Cells) > n {
+			n = len(row.Cells)
+		}
+	}
+	return n
+}
+
+// AllRows returns the header rows followed by the rows
+func (t *Table) AllRows() []*TableRow {
+	return append(append([]*TableRow(nil), t.Header...), t.Rows...)
+}
+
+// ColumnAlign returns the alignment of the column, as declared by its first cell with an alignment, or AlignLeft.
+func (t *Table) ColumnAlign(col int) string {
+	for _, row := range t.AllRows() {
+		if col < len(row.Cells) && row.Cells[col].Align != "" {
+			return row.Cells[col].Align
+		}
+	}
+	return AlignLeft
+}
+
+func (t *Table) Type() string {
+	return TableType
+}
+
+func (t *Table) children() []Discriminator {
+	var res []Discriminator
+	for _, row := range t.AllRows() {
+		res = append(res, row)
+	}
+	return res
+}
+
+func (t *Table) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = t.Type()
+	optSet(m, "caption", t.Caption)
+	optSet(m, "placement", t.Placement)
+	if len(t.Header) > 0 {
+		m["header"] = toJson(t.Header)
+	}
+	m["rows"] = toJson(t.Rows)
+	return m
+}
+
+func (t *Table) fromJson(m map[string]interface{}) {
+	t.Caption = optString(m, "caption")
+	t.Placement = optString(m, "placement")
+	t.Header = tableRowsFromJson(m["header"])
+	t.Rows = tableRowsFromJson(m["rows"])
+}
+
+func tableRowsFromJson(v interface{}) []*TableRow {
+	var res []*TableRow
+	for _, obj := range assertObjList(v) {
+		if row, ok := fromJson(obj).(*TableRow); ok {
+			res = append(res, row)
+		}
+	}
+	return res
+}
+
+// A TableRow is a single row of a Table
+type TableRow struct {
+	Cells []*TableCell
+}
+
+func (r *TableRow) Type() string {
+	return TableRowType
+}
+
+func (r *TableRow) children() []Discriminator {
+	var res []Discriminator
+	for _, cell := range r.Cells {
+		res = append(res, cell)
+	}
+	return res
+}
+
+func (r *TableRow) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = r.Type()
+	m["cells"] = toJson(r.Cells)
+	return m
+}
+
+func (r *TableRow) fromJson(m map[string]interface{}) {
+	r.Cells = nil
+	for _, obj := range assertObjList(m["cells"]) {
+		if cell, ok := fromJson(obj).(*TableCell); ok {
+			r.Cells = append(r.Cells, cell)
+		}
+	}
+}
+
+// A TableCell contains arbitrary content of a TableRow
+type TableCell struct {
+	Align string // Align is empty, AlignLeft, AlignCenter or AlignRight
+	Body  []Discriminator
+}
+
+// Cell creates a table cell
+func Cell(body ...Discriminator) *TableCell {
+	return &TableCell{Body: body}
+}
+
+func (c *TableCell) Add(body ...Discriminator) *TableCell {
+	c.Body = append(c.Body, body...)
+	return c
+}
+
+func (c *TableCell) Type() string {
+	return TableCellType
+}
+
+func (c *TableCell) children() []Discriminator {
+	return c.Body
+}
+
+func (c *TableCell) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = c.Type()
+	optSet(m, "align", c.Align)
+	m["body"] = toJson(c.Body)
+	return m
+}
+
+func (c *TableCell) fromJson(m map[string]interface{}) {
+	c.Align = optString(m, "align")
+	c.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		c.Body = append(c.Body, fromJson(obj))
+	}
+}
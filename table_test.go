@@ -0,0 +1,98 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestTable() *Table {
+	tbl := NewTable().AddHeader(Cell(Text("name")), &TableCell{Align: AlignRight, Body: []Discriminator{Text("size")}})
+	tbl.AddRow(Cell(Bold(Text("a.go"))), Cell(Text("12")))
+	tbl.AddRow(Cell(Text("b.go")), Cell().Add(Text("1024")))
+	tbl.Caption = "files"
+	return tbl
+}
+
+func TestTableRoundTrip(t *testing.T) {
+	tbl := newTestTable()
+	tbl.Placement = "htbp"
+	got := roundTrip(t, tbl)
+	if !reflect.DeepEqual(tbl, got) {
+		t.Fatalf("expected %s but got %s", debugJson(tbl.toJson()), debugJson(got.toJson()))
+	}
+	if cells := Collect(got, TableCellType); len(cells) != 6 {
+		t.Fatalf("expected 6 cells but got %d", len(cells))
+	}
+
+	text := renderText(t, `{{range .Rows}}{{range .Cells}}[{{range .Body}}{{str .}}{{end}}]{{end}};{{end}}`, &Table{Rows: tbl.Rows[1:]})
+	if text != "[b.go][1024];" {
+		t.Fatal(text)
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	doc := &Document{}
+	doc.Add(&Image{Src: "a.png"}, newTestTable())
+	doc.NumberFloats()
+	tbl := doc.Body[1].(*Table)
+	if tbl.Number != 1 || floatNumber(tbl) != 1 {
+		t.Fatalf("tables must have their own counter, got %d", tbl.Number)
+	}
+
+	html := renderHTML(tbl)
+	if !strings.Contains(html, "<caption>Table 1: files</caption>") ||
+		!strings.Contains(html, "<thead>\n<tr><th>name</th><th style=\"text-align: right\">size</th></tr>\n</thead>") ||
+		!strings.Contains(html, "<tr><td><strong>a.go</strong></td><td>12</td></tr>") {
+		t.Fatal(html)
+	}
+
+	latex := renderLatex(tbl)
+	if !strings.Contains(latex, "\\begin{tabular}{lr}\nname & size \\\\\n\\hline\n\\textbf{a.go} & 12 \\\\\n") ||
+		!strings.Contains(latex, "\\caption{files}") {
+		t.Fatal(latex)
+	}
+
+	adoc, err := (&Document{Body: []Discriminator{tbl}}).ToAsciiDoc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(adoc), ".files\n[cols=\"<,>\",options=\"header\"]\n|===\n|name |size\n|**a.go** |12\n") {
+		t.Fatal(string(adoc))
+	}
+}
+
+func TestPlainTextTable(t *testing.T) {
+	tbl := NewTable().AddHeader(Cell(Text("word")), &TableCell{Align: AlignRight, Body: []Discriminator{Text("n")}})
+	tbl.AddRow(Cell(Text("\u65e5\u672c\u8a9e")), Cell(Text("1")))
+	tbl.AddRow(Cell(Text("abc")), Cell(Text("200")))
+
+	want := "word   |   n\n-------+----\n\u65e5\u672c\u8a9e |   1\nabc    | 200\n"
+	if got := PlainText(tbl); got != want {
+		t.Fatalf("expected\n%s\nbut got\n%s", want, got)
+	}
+}
+
+func TestCheckTablePlacement(t *testing.T) {
+	tbl := newTestTable()
+	tbl.Placement = "top"
+	if errs := CheckPlacements(&Document{Body: []Discriminator{tbl}}); len(errs) != 1 {
+		t.Fatalf("expected a single error but got %v", errs)
+	}
+}
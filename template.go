@@ -22,113 +22,441 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	text "text/template"
+	"time"
 )
 
 const htmlTemplate = ".gohtml"
 const textTemplate = ".tmpl"
 
+// The default modes of generated files and directories
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// The supported line endings of Template.LineEnding
+const (
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+)
+
 type Template struct {
 	dir      string
 	buildDir string
 	html     *html.Template
 	text     *text.Template
 	files    []*File
+	timings  map[string]time.Duration // timings of the last Build by phase
+	warnings []error                  // warnings of the last Build, which did not fail it
+	nav      *navigation              // nav of the current model
+
+	fragments []sourceFragment   // fragments are the renderer outputs of the current file, see EmitSourceMap
+	paths     map[uintptr]string // paths are the locations of the elements of the current model
+
+	// Secrets are returned by the secret template function and redacted from any logged output.
+	Secrets map[string]string
+
+	// StrictUnknown lets the fallback template function fail instead of rendering the plain text of an element.
+	StrictUnknown bool
+
+	// LineEnding converts the line endings of all applied html and text templates into either LineEndingLF or
+	// LineEndingCRLF. The default keeps the line endings as they are.
+	LineEnding string
+
+	// EnsureFinalNewline appends a line ending to each applied html and text template, if missing.
+	EnsureFinalNewline bool
+
+	// FileMode is applied to all generated files, the default is DefaultFileMode.
+	FileMode os.FileMode
+
+	// DirMode is applied to all generated directories, the default is DefaultDirMode.
+	DirMode os.FileMode
+
+	// IncludeIntermediate also returns the generated .tex and .log files of a latexmk build, even if it failed.
+	IncludeIntermediate bool
+
+	// Ignore contains the glob patterns of the files and folders, which have been skipped by ReadTemplate.
+	Ignore []string
+
+	// NoAutobuild returns the rendered files without running latexmk or make, e.g. to get the latex sources only.
+	NoAutobuild bool
+
+	// MakeTarget is passed to make, if the template contains a Makefile. The default builds the first target.
+	MakeTarget string
+
+	// HTMLCodeCopyButton adds a button to each code block rendered by the renderHTML template function, which
+	// copies the code into the clipboard.
+	HTMLCodeCopyButton bool
+
+	// ResolveEmbeds lets the oembed template function request the embedding html from the oEmbed api of the
+	// provider. By default, no network access happens and the html is constructed for known providers. There is no
+	// safe mode, which would sanitize the returned html, so it is inserted as is.
+	ResolveEmbeds bool
+
+	// EmitSourceMap writes a json file next to each generated file, e.g. index.html.map.json, which relates the
+	// byte ranges and lines of the file to the paths of the elements, which produced them. Only the output of the
+	// renderHTML and renderLatex functions is mapped. Offsets refer to the output before LineEnding is applied.
+	EmitSourceMap bool
+
+	// Deps are the generated files of other build rules by rule name, which are available to the template by the
+	// deps function, see BuildRule.DependsOn.
+	Deps map[string][]string
+
+	// CodeTheme is the name of the theme of the codeThemeCSS and codeThemeLatex template functions, the default is
+	// DefaultCodeTheme.
+	CodeTheme string
+
+	// Incremental keeps the copies of static files, like css or js, from the last build into the same build dir,
+	// as long as their sources have the same size and modification time. All other files are removed and rendered
+	// again.
+	Incremental bool
+
+	// Assets are copied into the build dir before rendering, by their slash separated relative target path.
+	Assets map[string]string
 }
 
+// DefaultIgnore contains the glob patterns of files and folders, which are usually part of a template repository but
+// not of the output. A template like README.md.tmpl is kept, because it generates a file of the output.
+var DefaultIgnore = []string{".git", ".github", "node_modules", "README*", "LICENSE*", ".DS_Store"}
+
 // ReadTemplate creates a project based on an existing and parsable template folder structure. Empty and hidden folders
-// are ignored.
+// and all files and folders matching the DefaultIgnore patterns are ignored.
 func ReadTemplate(dir string, buildDir string) (*Template, error) {
+	return ReadTemplateIgnore(dir, buildDir, DefaultIgnore)
+}
+
+// ReadTemplateIgnore is like ReadTemplate but skips the files and folders, whose name or slash separated path
+// relative to dir matches any of the given glob patterns.
+func ReadTemplateIgnore(dir string, buildDir string, ignore []string) (*Template, error) {
+	return readTemplates([]string{dir}, buildDir, ignore)
+}
+
+// ReadTemplates reads multiple template roots as a single template, e.g. a common base and a project specific
+// overlay. A file of a later root overrides the file with the same relative path of any earlier root.
+func ReadTemplates(dirs []string, buildDir string) (*Template, error) {
+	return readTemplates(dirs, buildDir, DefaultIgnore)
+}
+
+func readTemplates(dirs []string, buildDir string, ignore []string) (*Template, error) {
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no template path")
+	}
+	for _, dir := range dirs {
+		if !IsDir(dir) {
+			return nil, fmt.Errorf("template path is not a directory: %s", dir)
+		}
+	}
 	prj := &Template{
-		dir:      dir,
+		dir:      dirs[0],
 		html:     html.New("/html/"),
 		text:     text.New("/text/"),
 		buildDir: buildDir,
+		Ignore:   ignore,
 	}
 	prj.text.Funcs(text.FuncMap{
-		"escapeLatex": EscapeLatex,
-		"typeOf":      typeOfName,
-		"isType":      is,
-		"str":         strOf,
+		"escapeLatex":        EscapeLatex,
+		"wrapURL":            WrapURL,
+		"typeOf":             typeOfName,
+		"isType":             is,
+		"str":                strOf,
+		"secret":             prj.secret,
+		"fallback":           prj.fallback,
+		"preserveWhitespace": PreserveWhitespace,
+		"requiredPreamble":   RequiredPreamble,
+		"requiredCSS":        RequiredCSS,
+		"floatNumber":        floatNumber,
+		"renderHTML":         prj.renderHTML,
+		"codeBlock":          codeBlock,
+		"codeThemeCSS":       prj.codeThemeCSS,
+		"codeThemeLatex":     prj.codeThemeLatex,
+		"oembed":             prj.oembed,
+		"href":               hrefOf,
+		"deps":               prj.deps,
+		"renderLatex":        prj.renderLatex,
+		"renderMarkdown":     renderMarkdown,
+		"initials":           initials,
+		"parent":             prj.parent,
+		"siblingIndex":       prj.siblingIndex,
+		"breadcrumb":         prj.breadcrumb,
 	})
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("failed to walk path %s: %w", path, err)
-		}
-		if info.IsDir() && strings.HasPrefix(info.Name(), ".") || path == buildDir {
-			return filepath.SkipDir
-		}
-		if !info.IsDir() {
-			if info.Name() == ".DS_Store" {
+	// the files by relative path, a later root replaces the file but keeps its position
+	var order []string
+	roots := make(map[string]string)
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("failed to walk path %s: %w", path, err)
+			}
+			if info.IsDir() && strings.HasPrefix(info.Name(), ".") || path == buildDir {
+				return filepath.SkipDir
+			}
+			if path != dir && prj.isIgnored(dir, path) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
 				return nil
 			}
-			file, err := NewFile(prj, path)
-			if err != nil {
-				return fmt.Errorf("failed to scan file: %w", err)
+			if !info.IsDir() {
+				rel := path[len(dir):]
+				if _, ok := roots[rel]; !ok {
+					order = append(order, rel)
+				}
+				roots[rel] = dir
 			}
-			prj.files = append(prj.files, file)
+			return nil
+		})
+		if err != nil {
+			return prj, fmt.Errorf("failed to list template files: %w", err)
 		}
-		return nil
-	})
-	if err != nil {
-		return prj, fmt.Errorf("failed to list template files: %w", err)
 	}
+	for _, rel := range order {
+		prj.dir = roots[rel]
+		file, err := NewFile(prj, roots[rel]+rel)
+		if err != nil {
+			return prj, fmt.Errorf("failed to list template files: failed to scan file: %w", err)
+		}
+		prj.files = append(prj.files, file)
+	}
+	prj.dir = dirs[len(dirs)-1]
 	return prj, nil
 }
 
 // Build applies the model to the template project. In general, all files are just copied over, however *.gohtml
 // and *.tmpl files are applied as html or text template definitions with the actual model. The resulting filename
-// is without the template extension, e.g. myfile.tex.tmpl will result in a file named myfile.tex.
-// The generated files from the template are returned.
+// is without the template extension, e.g. myfile.tex.tmpl will result in a file named myfile.tex. A file name
+// may itself contain template actions, e.g. manual-{{.Title}}.pdf, which are evaluated against the model.
+// The generated files from the template are returned, including those of nested folders. If the project has been
+// built by latexmk or make, only the produced pdf files are returned. If IncludeIntermediate is set and the autobuild fails, the
+// intermediate files are returned together with the error.
 func (p *Template) Build(model interface{}) ([]string, error) {
 	dstDir := p.buildDir
-	err := os.RemoveAll(dstDir)
+	upToDate := make(map[string]bool)
+	if p.Incremental {
+		for _, file := range p.files {
+			// the name must be resolved for this model, otherwise the output of the previous build is checked
+			if err := file.resolveName(model); err != nil {
+				return nil, err
+			}
+			if file.isUpToDate() {
+				upToDate[file.dstFile()] = true
+			}
+		}
+	}
+	err := removeFilesExcept(dstDir, upToDate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to remove build dir %s: %w", dstDir, err)
+		return nil, fmt.Errorf("failed to clean build dir %s: %w", dstDir, err)
 	}
-	err = os.MkdirAll(dstDir, os.ModePerm)
+	err = os.MkdirAll(dstDir, p.dirMode())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create build dir %s: %w", dstDir, err)
 	}
+	for rel, src := range p.Assets {
+		dst := filepath.Join(dstDir, filepath.FromSlash(rel))
+		if !isWithinDir(dstDir, dst) {
+			return nil, fmt.Errorf("asset %s is outside of the build dir", rel)
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), p.dirMode()); err != nil {
+			return nil, fmt.Errorf("failed to create asset dir %s: %w", filepath.Dir(dst), err)
+		}
+		if err := CopyFileMode(src, dst, p.fileMode()); err != nil {
+			return nil, fmt.Errorf("failed to copy asset %s: %w", src, err)
+		}
+	}
+	p.timings = make(map[string]time.Duration)
+	p.warnings = nil
+	start := time.Now()
+	p.nav = nil
+	p.paths = make(map[uintptr]string)
+	if root, ok := model.(Discriminator); ok {
+		p.nav = newNavigation(root)
+		if p.EmitSourceMap {
+			walkPath(root, func(d Discriminator, path string) {
+				if key, ok := identity(d); ok {
+					p.paths[key] = path
+				}
+			})
+		}
+	}
 	for _, file := range p.files {
+		if upToDate[file.dstFile()] {
+			continue
+		}
 		err := file.Apply(model)
 		if err != nil {
 			return nil, fmt.Errorf("failed to build: %w", err)
 		}
+		if _, isCopy := file.transformer.(*CopyTransformer); isCopy && p.Incremental {
+			// the modification time of the source marks the copy as up to date for the next build
+			if info, err := os.Stat(file.srcFile); err == nil {
+				_ = os.Chtimes(file.dstFile(), info.ModTime(), info.ModTime())
+			}
+		}
+	}
+	p.timings[PhaseRender] = time.Since(start)
+
+	start = time.Now()
+	files, err := p.autobuild()
+	p.timings[PhaseAutobuild] = time.Since(start)
+	return files, err
+}
+
+// isIgnored returns true, if the name or the path of the file relative to its root matches any Ignore pattern.
+// A template file, like README.md.tmpl, only matches a pattern for templates, like README*.tmpl, because it
+// generates a file of the output. Its folder is matched by any pattern.
+func (p *Template) isIgnored(root string, fname string) bool {
+	rel, err := filepath.Rel(root, fname)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	isTemplate := strings.HasSuffix(rel, htmlTemplate) || strings.HasSuffix(rel, textTemplate)
+	for _, pattern := range p.Ignore {
+		if isTemplate && !strings.HasSuffix(pattern, htmlTemplate) && !strings.HasSuffix(pattern, textTemplate) {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(fname)); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
 	}
-	return p.autobuild()
+	return false
 }
 
+func (p *Template) fileMode() os.FileMode {
+	if p.FileMode == 0 {
+		return DefaultFileMode
+	}
+	return p.FileMode
+}
+
+func (p *Template) dirMode() os.FileMode {
+	if p.DirMode == 0 {
+		return DefaultDirMode
+	}
+	return p.DirMode
+}
+
+// secret returns the value of the named secret or fails
+func (p *Template) secret(name string) (string, error) {
+	if v, ok := p.Secrets[name]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("undefined secret '%s'", name)
+}
+
+// fallback is used by templates for elements of unsupported types. It returns the plain text of the element and
+// its children, so that no content gets lost. If StrictUnknown is set, an error is returned instead.
+func (p *Template) fallback(d Discriminator) (string, error) {
+	if p.StrictUnknown {
+		return "", fmt.Errorf("template does not support type '%s'", d.Type())
+	}
+	return PlainText(d), nil
+}
+
+// parent returns the container of the element within the model or nil
+func (p *Template) parent(d Discriminator) Discriminator {
+	return p.nav.parent(d)
+}
+
+// siblingIndex returns the index of the element within the children of its parent or -1
+func (p *Template) siblingIndex(d Discriminator) int {
+	return p.nav.siblingIndex(d)
+}
+
+// breadcrumb returns the workspace, documents and chapters from the model root down to the element
+func (p *Template) breadcrumb(d Discriminator) []Discriminator {
+	return p.nav.breadcrumb(d)
+}
+
+// initials returns the initials of an author, see Author.Initials.
+func initials(a *Author) string {
+	return a.Initials()
+}
+
+// latexmkCmd is the command to build latex projects
+var latexmkCmd = "latexmk"
+
+// makeCmd is the command to build projects with a Makefile
+var makeCmd = "make"
+
+// autobuild runs the build tool, which is detected by its configuration file in the build dir. A latexmkrc is
+// preferred over a Makefile. Without any build tool or if NoAutobuild is set, all generated files are returned.
 func (p *Template) autobuild() ([]string, error) {
+	if p.NoAutobuild {
+		return listFiles(p.buildDir)
+	}
 	if _, err := os.Stat(filepath.Join(p.buildDir, "latexmkrc")); err == nil {
 		fmt.Println("latexmkrc")
-		cmd := exec.Command("latexmk")
-		cmd.Dir = p.buildDir
-		cmd.Env = os.Environ()
-		res, err := cmd.CombinedOutput()
-		fmt.Println(string(res))
-		if err != nil {
-			return nil, fmt.Errorf("failed to build latex project in %s: %w", p.buildDir, err)
+		return p.runBuildTool(latexmkCmd)
+	}
+	if _, err := os.Stat(filepath.Join(p.buildDir, "Makefile")); err == nil {
+		if _, err := exec.LookPath(makeCmd); err != nil {
+			return nil, fmt.Errorf("cannot build Makefile project in %s: %w", p.buildDir, err)
 		}
-		files, err := listRootFiles(p.buildDir)
-		if err != nil {
-			return nil, err
+		var args []string
+		if p.MakeTarget != "" {
+			args = append(args, p.MakeTarget)
 		}
-		var paths []string
-		for _, f := range files {
-			if strings.HasSuffix(f, ".pdf") {
-				paths = append(paths, f)
-			}
+		return p.runBuildTool(makeCmd, args...)
+	}
+	fmt.Println("autobuild not supported")
+	return listFiles(p.buildDir)
+}
+
+// runBuildTool executes the command in the build dir and returns the produced pdf files
+func (p *Template) runBuildTool(name string, args ...string) ([]string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = p.buildDir
+	cmd.Env = os.Environ()
+	res, buildErr := cmd.CombinedOutput()
+	fmt.Println(redact(string(res), p.Secrets))
+	if buildErr != nil && !p.IncludeIntermediate {
+		return nil, fmt.Errorf("failed to build project in %s with %s: %w", p.buildDir, name, buildErr)
+	}
+	files, err := listRootFiles(p.buildDir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".pdf") || p.IncludeIntermediate && isLatexIntermediate(f) {
+			paths = append(paths, f)
 		}
-		return paths, nil
-	} else {
-		fmt.Println("autobuild not supported")
 	}
+	if buildErr != nil {
+		return paths, fmt.Errorf("failed to build project in %s with %s: %w", p.buildDir, name, buildErr)
+	}
+	return paths, nil
+}
 
-	return listRootFiles(p.buildDir)
+// isLatexIntermediate returns true for generated latex sources and logs.
+func isLatexIntermediate(fname string) bool {
+	return strings.HasSuffix(fname, ".tex") || strings.HasSuffix(fname, ".log")
+}
+
+// listFiles returns all files of the directory recursively, but not the directories themselves
+func listFiles(dir string) ([]string, error) {
+	var res []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk path %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			res = append(res, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files from %s: %w", dir, err)
+	}
+	return res, nil
 }
 
 func listRootFiles(dir string) ([]string, error) {
@@ -142,3 +470,59 @@ func listRootFiles(dir string) ([]string, error) {
 	}
 	return res, nil
 }
+
+// removeFilesExcept removes the dir and all of its content, except the files to keep and their parent folders
+func removeFilesExcept(dir string, keep map[string]bool) error {
+	if len(keep) == 0 {
+		return os.RemoveAll(dir)
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || keep[path] {
+			return nil
+		}
+		return os.Remove(path)
+	})
+}
+
+// codeTheme returns the selected theme or the default one, if it is unknown
+func (p *Template) codeTheme() CodeTheme {
+	if t, ok := LookupCodeTheme(p.CodeTheme); ok {
+		return t
+	}
+	t, _ := LookupCodeTheme(DefaultCodeTheme)
+	return t
+}
+
+// codeThemeCSS is the template function, which returns the css of the selected code theme
+func (p *Template) codeThemeCSS() string {
+	return p.codeTheme().CSS()
+}
+
+// codeThemeLatex is the template function, which returns the listings style of the selected code theme
+func (p *Template) codeThemeLatex() string {
+	return p.codeTheme().LatexStyle()
+}
+
+// oembed is the template function, which returns the embedding html, see ResolveEmbeds. A failed request is kept
+// as warning and falls back to the constructed html.
+func (p *Template) oembed(e *Embed) string {
+	if p.ResolveEmbeds {
+		res, err := e.resolveOEmbed()
+		if err == nil {
+			return res
+		}
+		p.warnings = append(p.warnings, err)
+	}
+	return e.EmbedHTML()
+}
+
+// deps returns the generated files of the rules, which the build rule depends on
+func (p *Template) deps() map[string][]string {
+	return p.Deps
+}
@@ -17,8 +17,16 @@
 package wdydoc
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestOpen(t *testing.T) {
@@ -39,6 +47,173 @@ func TestOpen(t *testing.T) {
 	}
 }
 
+func TestTemplateIsFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"index.txt.tmpl": "{{.Title}}"})
+	file := filepath.Join(dir, "index.txt.tmpl")
+
+	build, err := NewBuild(&Workspace{}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: file})
+	err = build.Apply()
+	if err == nil || !strings.Contains(err.Error(), "template path is not a directory: "+file) {
+		t.Fatalf("expected a descriptive error but got %v", err)
+	}
+
+	_, err = ReadTemplate(file, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "template path is not a directory") {
+		t.Fatalf("expected a descriptive error but got %v", err)
+	}
+}
+
+// stubLatexmk replaces the latexmk command by a shell script for the duration of the test.
+func stubLatexmk(t *testing.T, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported")
+	}
+	fname := filepath.Join(t.TempDir(), "latexmk")
+	if err := ioutil.WriteFile(fname, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := latexmkCmd
+	latexmkCmd = fname
+	t.Cleanup(func() {
+		latexmkCmd = old
+	})
+}
+
+func TestIncludeIntermediate(t *testing.T) {
+	stubLatexmk(t, "echo log > main.log && touch main.pdf")
+	tplDir := writeFiles(t, map[string]string{"latexmkrc": "", "main.tex.tmpl": "{{.Title}}"})
+
+	for _, include := range []bool{false, true} {
+		outDir := t.TempDir()
+		build, err := NewBuild(&Workspace{Title: "latex"}, outDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		build.IncludeIntermediate = include
+		build.AddRule(&BuildRule{Template: tplDir, Name: "book"})
+		if err := build.Apply(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(outDir, "book", "main.pdf")); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"main.tex", "main.log"} {
+			_, err := os.Stat(filepath.Join(outDir, "book", name))
+			if include && err != nil {
+				t.Fatalf("expected %s: %v", name, err)
+			}
+			if !include && err == nil {
+				t.Fatalf("did not expect %s", name)
+			}
+		}
+	}
+}
+
+func TestIncludeIntermediateOnFailure(t *testing.T) {
+	stubLatexmk(t, "exit 1")
+	tplDir := writeFiles(t, map[string]string{"latexmkrc": "", "main.tex.tmpl": "{{.Title}}"})
+	outDir := t.TempDir()
+	build, err := NewBuild(&Workspace{Title: "latex"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.IncludeIntermediate = true
+	build.AddRule(&BuildRule{Template: tplDir, Name: "book"})
+	if err := build.Apply(); err == nil {
+		t.Fatal("expected latexmk failure")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "book", "main.tex")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// captureStdout returns everything written to stdout while f is executed.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		out <- string(b)
+	}()
+	defer func() {
+		os.Stdout = old
+	}()
+	f()
+	_ = w.Close()
+	return <-out
+}
+
+func TestSecrets(t *testing.T) {
+	stubLatexmk(t, "cat main.tex && touch main.pdf")
+	tplDir := writeFiles(t, map[string]string{"latexmkrc": "", "main.tex.tmpl": `key={{secret "apikey"}}`})
+	build, err := NewBuild(&Workspace{}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.IncludeIntermediate = true
+	build.Secrets = map[string]string{"apikey": "s3cr3t-value"}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "book"})
+
+	stdout := captureStdout(t, func() {
+		err = build.Apply()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(build.dir, "book", "main.tex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "key=s3cr3t-value" {
+		t.Fatalf("expected secret in template but got %s", string(b))
+	}
+	if strings.Contains(stdout, "s3cr3t-value") || !strings.Contains(stdout, "key=***") {
+		t.Fatalf("expected redacted log output but got %s", stdout)
+	}
+}
+
+func TestUndefinedSecret(t *testing.T) {
+	tpl := writeFiles(t, map[string]string{"out.txt.tmpl": `{{secret "missing"}}`})
+	p, err := ReadTemplate(tpl, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Build(&Workspace{}); err == nil || !strings.Contains(err.Error(), "undefined secret 'missing'") {
+		t.Fatalf("expected undefined secret error but got %v", err)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	doc := &Document{}
+	doc.Add(Text("known "), Underline(Text("unknown "), Bold(Text("nested"))))
+	tpl := `{{range .Body}}{{if isType . "text"}}{{.Value}}{{else}}{{fallback .}}{{end}}{{end}}`
+
+	if got := renderText(t, tpl, doc); got != "known unknown nested" {
+		t.Fatalf("expected the text of the unknown element but got '%s'", got)
+	}
+
+	dir := writeFiles(t, map[string]string{"out.txt.tmpl": tpl})
+	p, err := ReadTemplate(dir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.StrictUnknown = true
+	if _, err := p.Build(doc); err == nil || !strings.Contains(err.Error(), "does not support type 'underline'") {
+		t.Fatalf("expected strict error but got %v", err)
+	}
+}
+
 func createModel(t *testing.T) *Workspace {
 	t.Helper()
 
@@ -88,3 +263,315 @@ func createModel(t *testing.T) *Workspace {
 
 	return rereadWs
 }
+
+func TestMakefile(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make is not installed")
+	}
+	makefile := "all:\n\ttouch all.pdf\n\nbook:\n\techo book > book.pdf\n"
+	tplDir := writeFiles(t, map[string]string{"Makefile": makefile, "main.tex.tmpl": "{{.Title}}"})
+
+	for target, pdf := range map[string]string{"": "all.pdf", "book": "book.pdf"} {
+		outDir := t.TempDir()
+		build, err := NewBuild(&Workspace{Title: "make"}, outDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		build.MakeTarget = target
+		build.AddRule(&BuildRule{Template: tplDir, Name: "book"})
+		if err := build.Apply(); err != nil {
+			t.Fatal(err)
+		}
+		files, err := ioutil.ReadDir(filepath.Join(outDir, "book"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Name() != pdf {
+			t.Fatalf("expected only %s but got %v", pdf, files)
+		}
+	}
+}
+
+func TestMakefileWithoutMake(t *testing.T) {
+	old := makeCmd
+	makeCmd = "wdydoc-missing-make"
+	defer func() {
+		makeCmd = old
+	}()
+	tplDir := writeFiles(t, map[string]string{"Makefile": "all:\n"})
+	p, err := ReadTemplate(tplDir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Build(&Workspace{}); err == nil {
+		t.Fatal("expected missing make to fail")
+	}
+}
+
+func TestSkipAutobuild(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	stubLatexmk(t, "touch "+marker+" main.pdf")
+	tplDir := writeFiles(t, map[string]string{"latexmkrc": "", "main.tex.tmpl": "{{.Title}}"})
+	outDir := t.TempDir()
+	build, err := NewBuild(&Workspace{Title: "sources"}, outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Template: tplDir, Name: "book", SkipAutobuild: true})
+	if err := build.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatal("latexmk must not run")
+	}
+	for _, name := range []string{"main.tex", "latexmkrc"} {
+		if _, err := os.Stat(filepath.Join(outDir, "book", name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTemplateIgnore(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{
+		"index.html.gohtml":         "{{.Title}}",
+		"node_modules/lib/index.js": "lib",
+		"README.md":                 "readme",
+		"LICENSE":                   "license",
+		"assets/drafts/draft.txt":   "draft",
+		"assets/logo.png":           "png",
+		".github/workflows/ci.yml":  "ci",
+		"docs/node_modules/x/y.js":  "nested",
+		"docs/README-nested.md":     "readmes are ignored by name",
+		"README.txt.tmpl":           "generates a readme",
+		"notes.txt.tmpl":            "ignored by a template pattern",
+	})
+	p, err := ReadTemplateIgnore(tplDir, t.TempDir(), append(DefaultIgnore, "assets/drafts", "notes*.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range p.files {
+		rel, err := filepath.Rel(tplDir, f.srcFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, filepath.ToSlash(rel))
+	}
+	if strings.Join(names, ",") != "README.txt.tmpl,assets/logo.png,index.html.gohtml" {
+		t.Fatalf("unexpected template files %v", names)
+	}
+}
+
+func TestIncrementalCopies(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"style.css": "body {}", "index.txt.tmpl": "{{.Title}}"})
+	buildDir := filepath.Join(t.TempDir(), "build")
+	tpl, err := ReadTemplate(tplDir, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.Incremental = true
+	tpl.NoAutobuild = true
+	if _, err := tpl.Build(&Document{Title: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a copy, which has not been rewritten, keeps this marker of the same size and time
+	css := filepath.Join(buildDir, "style.css")
+	info, err := os.Stat(css)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(css, []byte("kept {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(css, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(buildDir, "stale.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := tpl.Build(&Document{Title: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected stale files to be removed but got %v", files)
+	}
+	if b, _ := ioutil.ReadFile(css); string(b) != "kept {}" {
+		t.Fatalf("expected the unchanged asset to be kept but got %s", b)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(buildDir, "index.txt")); string(b) != "second" {
+		t.Fatalf("expected the template to be rendered again but got %s", b)
+	}
+
+	later := info.ModTime().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(tplDir, "style.css"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpl.Build(&Document{}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(css); string(b) != "body {}" {
+		t.Fatalf("expected the changed asset to be copied but got %s", b)
+	}
+}
+
+func TestIncrementalFilenameTemplate(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"logo-{{.Title}}.png": "png"})
+	buildDir := filepath.Join(t.TempDir(), "build")
+	tpl, err := ReadTemplate(tplDir, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.Incremental = true
+	tpl.NoAutobuild = true
+	for _, title := range []string{"first", "second"} {
+		files, err := tpl.Build(&Document{Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := filepath.Join(buildDir, "logo-"+title+".png")
+		if len(files) != 1 || files[0] != want {
+			t.Fatalf("expected only %s but got %v", want, files)
+		}
+		if b, _ := ioutil.ReadFile(want); string(b) != "png" {
+			t.Fatalf("expected the copy of the logo but got %s", b)
+		}
+	}
+}
+
+func TestReadTemplates(t *testing.T) {
+	base := writeFiles(t, map[string]string{
+		"index.txt.tmpl":  "base {{.Title}}",
+		"style.css":       "base {}",
+		"assets/logo.png": "png",
+	})
+	overlay := writeFiles(t, map[string]string{
+		"style.css":        "overlay {}",
+		"assets/extra.txt": "extra",
+	})
+	buildDir := filepath.Join(t.TempDir(), "build")
+	tpl, err := ReadTemplates([]string{base, overlay}, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.NoAutobuild = true
+	files, err := tpl.Build(&Document{Title: "doc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Fatalf("expected merged files but got %v", files)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(buildDir, "style.css")); string(b) != "overlay {}" {
+		t.Fatalf("expected the overlay file to win but got %s", b)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(buildDir, "index.txt")); string(b) != "base doc" {
+		t.Fatalf("expected the base template but got %s", b)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(buildDir, "assets", "extra.txt")); string(b) != "extra" {
+		t.Fatalf("expected the overlay asset but got %s", b)
+	}
+}
+
+func TestFilenameTemplate(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"manual-{{.Title}}.txt.tmpl": "{{.Title}}"})
+	buildDir := filepath.Join(t.TempDir(), "build")
+	tpl, err := ReadTemplate(tplDir, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.NoAutobuild = true
+	files, err := tpl.Build(&Document{Title: "User Guide/../2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "manual-User-Guide-..-2.txt" {
+		t.Fatalf("expected the title in the file name but got %v", files)
+	}
+
+	if _, err := tpl.Build(&Document{Title: "/"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(buildDir, "manual-.txt")); string(b) != "/" {
+		t.Fatalf("expected a sanitized name but got %s", b)
+	}
+}
+
+func TestFilenameTraversal(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"{{.Title}}.txt.tmpl": "{{.Title}}"})
+	parent := t.TempDir()
+	buildDir := filepath.Join(parent, "build")
+	tpl, err := ReadTemplate(tplDir, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.NoAutobuild = true
+	for _, title := range []string{"../../escaped", "/etc/escaped", `..\escaped`} {
+		files, err := tpl.Build(&Document{Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || filepath.Dir(files[0]) != buildDir {
+			t.Fatalf("%s: expected a file within the build dir but got %v", title, files)
+		}
+	}
+	if matches, _ := filepath.Glob(filepath.Join(parent, "*escaped*")); len(matches) != 0 {
+		t.Fatalf("expected nothing outside of the build dir but got %v", matches)
+	}
+
+	for path, want := range map[string]bool{"build/a.txt": true, "build": true, "a.txt": false, "build/../a.txt": false, "build/..a": true} {
+		if got := isWithinDir("build", path); got != want {
+			t.Fatalf("%s: expected %v but got %v", path, want, got)
+		}
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{
+		"index.html.tmpl": "<html>\n{{renderHTML .}}</html>\n",
+		"plain.txt.tmpl":  "{{.Title}}",
+	})
+	buildDir := filepath.Join(t.TempDir(), "build")
+	tpl, err := ReadTemplate(tplDir, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.NoAutobuild = true
+	tpl.EmitSourceMap = true
+	doc := &Document{Id: "doc"}
+	doc.Add(Text("preface"))
+	doc.NewChapter("intro").Add(Text("hello"))
+	if _, err := tpl.Build(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(buildDir, "index.html"+sourceMapSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []sourceMapEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		t.Fatal(err)
+	}
+	html, _ := ioutil.ReadFile(filepath.Join(buildDir, "index.html"))
+	var chapter *sourceMapEntry
+	for i := range entries {
+		if entries[i].Type == ChapterType {
+			chapter = &entries[i]
+		}
+	}
+	if chapter == nil || chapter.Path != "doc/intro" {
+		t.Fatalf("expected the chapter in %s", b)
+	}
+	if got := string(html[chapter.Start:chapter.End]); !strings.HasPrefix(got, "<section>\n<h2>intro</h2>") {
+		t.Fatalf("expected the heading of the chapter but got %q", got)
+	}
+	if lines := strings.Split(string(html), "\n"); lines[chapter.StartLine-1] != "<section>" || lines[chapter.EndLine-1] != "</section>" {
+		t.Fatalf("unexpected lines %d-%d of\n%s", chapter.StartLine, chapter.EndLine, html)
+	}
+	if _, err := os.Stat(filepath.Join(buildDir, "plain.txt"+sourceMapSuffix)); err == nil {
+		t.Fatal("expected no source map without renderer output")
+	}
+}
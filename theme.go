@@ -0,0 +1,93 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import "fmt"
+
+// DefaultCodeTheme is used, if no CodeTheme has been selected
+const DefaultCodeTheme = "github"
+
+// A CodeTheme defines the colors of highlighted code by token kind. Colors are hex values without #, like 24292e.
+type CodeTheme struct {
+	Name       string
+	Background string
+	Foreground string
+	Keyword    string
+	String     string
+	Comment    string
+	Number     string
+}
+
+// codeThemes are the registered themes by name
+var codeThemes = map[string]CodeTheme{
+	"github": {
+		Name:       "github",
+		Background: "f6f8fa",
+		Foreground: "24292e",
+		Keyword:    "d73a49",
+		String:     "032f62",
+		Comment:    "6a737d",
+		Number:     "005cc5",
+	},
+	"monokai": {
+		Name:       "monokai",
+		Background: "272822",
+		Foreground: "f8f8f2",
+		Keyword:    "f92672",
+		String:     "e6db74",
+		Comment:    "75715e",
+		Number:     "ae81ff",
+	},
+}
+
+// RegisterCodeTheme declares a theme by its name. An existing theme is replaced. Registration is not thread safe and
+// should happen at initialization.
+func RegisterCodeTheme(t CodeTheme) {
+	codeThemes[t.Name] = t
+}
+
+// LookupCodeTheme returns the registered theme. The empty name is the DefaultCodeTheme.
+func LookupCodeTheme(name string) (CodeTheme, bool) {
+	if name == "" {
+		name = DefaultCodeTheme
+	}
+	t, ok := codeThemes[name]
+	return t, ok
+}
+
+// CSS returns the style definitions for code blocks, which are highlighted by their language class, e.g. with
+// highlight.js.
+func (t CodeTheme) CSS() string {
+	return fmt.Sprintf("pre code { background-color: #%s; color: #%s; }\n", t.Background, t.Foreground) +
+		fmt.Sprintf(".hljs-keyword { color: #%s; }\n", t.Keyword) +
+		fmt.Sprintf(".hljs-string { color: #%s; }\n", t.String) +
+		fmt.Sprintf(".hljs-comment { color: #%s; font-style: italic; }\n", t.Comment) +
+		fmt.Sprintf(".hljs-number { color: #%s; }", t.Number)
+}
+
+// LatexStyle returns a listings style with the name of the theme and selects it for all listings. It requires the
+// listings and xcolor packages.
+func (t CodeTheme) LatexStyle() string {
+	return fmt.Sprintf("\\lstdefinestyle{%s}{\n", t.Name) +
+		fmt.Sprintf("  backgroundcolor=\\color[HTML]{%s},\n", t.Background) +
+		fmt.Sprintf("  basicstyle=\\color[HTML]{%s}\\ttfamily,\n", t.Foreground) +
+		fmt.Sprintf("  keywordstyle=\\color[HTML]{%s}\\bfseries,\n", t.Keyword) +
+		fmt.Sprintf("  stringstyle=\\color[HTML]{%s},\n", t.String) +
+		fmt.Sprintf("  commentstyle=\\color[HTML]{%s}\\itshape\n", t.Comment) +
+		"}\n" +
+		fmt.Sprintf("\\lstset{style=%s}", t.Name)
+}
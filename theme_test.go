@@ -0,0 +1,65 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCodeTheme(t *testing.T) {
+	github, ok := LookupCodeTheme("")
+	if !ok || github.Name != DefaultCodeTheme {
+		t.Fatalf("expected the default theme but got %+v", github)
+	}
+	monokai, ok := LookupCodeTheme("monokai")
+	if !ok {
+		t.Fatal("expected the monokai theme")
+	}
+	if github.CSS() == monokai.CSS() || github.LatexStyle() == monokai.LatexStyle() {
+		t.Fatal("expected different styles")
+	}
+	if !strings.Contains(monokai.CSS(), ".hljs-keyword { color: #f92672; }") {
+		t.Fatal(monokai.CSS())
+	}
+
+	tplDir := writeFiles(t, map[string]string{"style.css.tmpl": "{{codeThemeCSS}}", "style.tex.tmpl": "{{codeThemeLatex}}"})
+	ws := &Workspace{}
+	ws.NewDocument().Id = "doc"
+	build, err := NewBuild(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.CodeTheme = "monokai"
+	build.AddRule(&BuildRule{Id: "doc", Template: tplDir, Name: "out"})
+	files, err := build.BuildToMemory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(files["out/style.css"]); got != monokai.CSS() {
+		t.Fatal(got)
+	}
+	if got := string(files["out/style.tex"]); !strings.HasSuffix(got, "\\lstset{style=monokai}") {
+		t.Fatal(got)
+	}
+
+	build.CodeTheme = "unknown"
+	if _, err := build.BuildToMemory(context.Background()); err == nil {
+		t.Fatal("expected an unknown theme to fail")
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// A Tooltip annotates inline content with a Note, e.g. to explain jargon. Html shows the note when hovering the
+// body, print targets like Latex degrade it to a footnote.
+type Tooltip struct {
+	Note string
+	Body []Discriminator
+}
+
+// WithTooltip creates the body annotated by the note
+func WithTooltip(note string, body ...Discriminator) *Tooltip {
+	return &Tooltip{Note: note, Body: body}
+}
+
+func (t *Tooltip) Add(body ...Discriminator) *Tooltip {
+	t.Body = append(t.Body, body...)
+	return t
+}
+
+func (t *Tooltip) Type() string {
+	return TooltipType
+}
+
+func (t *Tooltip) children() []Discriminator {
+	return t.Body
+}
+
+func (t *Tooltip) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = t.Type()
+	m["note"] = t.Note
+	m["body"] = toJson(t.Body)
+	return m
+}
+
+func (t *Tooltip) fromJson(m map[string]interface{}) {
+	t.Note = optString(m, "note")
+	t.Body = nil
+	for _, obj := range assertObjList(m["body"]) {
+		t.Body = append(t.Body, fromJson(obj))
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTooltip(t *testing.T) {
+	tip := WithTooltip("Portable Document Format", Bold(Text("PDF")), WithTooltip("nested", Text("!")))
+	if got := roundTrip(t, tip); !reflect.DeepEqual(tip, got) {
+		t.Fatalf("expected %s but got %s", debugJson(tip.toJson()), debugJson(got.toJson()))
+	}
+
+	if latex := renderLatex(tip); latex != `\textbf{PDF}!\footnote{nested}\footnote{Portable Document Format}` {
+		t.Fatal(latex)
+	}
+	html := renderHTML(tip)
+	if !strings.HasPrefix(html, `<span class="tooltip" title="Portable Document Format"><strong>PDF</strong>`) {
+		t.Fatal(html)
+	}
+	if text := PlainText(tip); text != "PDF!" {
+		t.Fatal(text)
+	}
+	if css := RequiredCSS(tip); !strings.Contains(css, ".tooltip") {
+		t.Fatal(css)
+	}
+}
@@ -0,0 +1,262 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"sort"
+	"strings"
+)
+
+// PromoteFirstHeading turns a single leading top level chapter into the title of the document, which is typical
+// for imported markdown files starting with a H1. The chapter is replaced by its body and all of its sub chapters
+// move up a level. Nothing is changed and false is returned, if the document already has a title, does not begin
+// with a chapter or contains further top level chapters.
+func (c *Document) PromoteFirstHeading() bool {
+	if c.Title != "" || len(c.Body) == 0 {
+		return false
+	}
+	first, ok := c.Body[0].(*Chapter)
+	if !ok || first.Level != 0 {
+		return false
+	}
+	for _, e := range c.Body[1:] {
+		if chap, ok := e.(*Chapter); ok && chap.Level == 0 {
+			return false
+		}
+	}
+	c.Title = first.Title
+	shiftLevels(first.Body, -1)
+	c.Body = append(append([]Discriminator(nil), first.Body...), c.Body[1:]...)
+	return true
+}
+
+// shiftLevels adds delta to the level of all chapters within the body, recursively
+func shiftLevels(body []Discriminator, delta int) {
+	for _, e := range body {
+		switch t := e.(type) {
+		case *Chapter:
+			t.Level += delta
+			shiftLevels(t.Body, delta)
+		case *Part:
+			shiftLevels(t.Body, delta)
+		}
+	}
+}
+
+// NormalizeLevels renumbers the levels of all chapters to remove gaps, e.g. a chapter of level 2 directly below
+// a chapter of level 0 gets level 1. The relative nesting and the tree itself are kept, only the levels change.
+func (c *Document) NormalizeLevels() {
+	normalizeLevels(c.Body, 0, func(chap *Chapter, level int) {
+		chap.Level = level
+	})
+}
+
+// normalizeLevels calculates the gapless level of each chapter. Top level chapters of the body start at the base
+// level and each following sibling with a higher level is treated as nested into the previous one.
+func normalizeLevels(body []Discriminator, base int, f func(chap *Chapter, level int)) {
+	type entry struct {
+		old, level int
+	}
+	var stack []entry
+	for _, e := range body {
+		if part, ok := e.(*Part); ok {
+			// each part starts a new hierarchy of top level chapters
+			stack = nil
+			normalizeLevels(part.Body, base, f)
+			continue
+		}
+		chap, ok := e.(*Chapter)
+		if !ok {
+			continue
+		}
+		for len(stack) > 0 && stack[len(stack)-1].old >= chap.Level {
+			stack = stack[:len(stack)-1]
+		}
+		level := base
+		if len(stack) > 0 {
+			level = stack[len(stack)-1].level + 1
+		}
+		stack = append(stack, entry{old: chap.Level, level: level})
+		normalizeLevels(chap.Body, level+1, f)
+		f(chap, level)
+	}
+}
+
+// SortResources stably reorders the documents of the workspace by "id" or "title", e.g. to get reproducible
+// outputs for generated workspaces. Other resources keep their positions and the documents only swap places among
+// themselves. Any other criterion is ignored.
+func (w *Workspace) SortResources(by string) {
+	var key func(doc *Document) string
+	switch by {
+	case "id":
+		key = func(doc *Document) string { return doc.Id }
+	case "title":
+		key = func(doc *Document) string { return doc.Title }
+	default:
+		return
+	}
+	var positions []int
+	var docs []*Document
+	for i, res := range w.Resources {
+		if doc, ok := res.(*Document); ok {
+			positions = append(positions, i)
+			docs = append(docs, doc)
+		}
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return key(docs[i]) < key(docs[j])
+	})
+	for i, pos := range positions {
+		w.Resources[pos] = docs[i]
+	}
+}
+
+// ListToChapters converts a nested list, e.g. an imported outline, into chapters. The text of each item becomes
+// the title of a chapter at the base level and the items of its nested lists become its sub chapters. A list,
+// which is an item on its own, is nested into the chapter of the previous item.
+func ListToChapters(l *List, baseLevel int) []*Chapter {
+	var res []*Chapter
+	for _, item := range l.Items {
+		if list, ok := item.(*List); ok {
+			if len(res) == 0 {
+				res = append(res, ListToChapters(list, baseLevel)...)
+				continue
+			}
+			prev := res[len(res)-1]
+			for _, c := range ListToChapters(list, baseLevel+1) {
+				prev.Body = append(prev.Body, c)
+			}
+			continue
+		}
+
+		body := []Discriminator{item}
+		if entry, ok := item.(*ListEntry); ok {
+			body = entry.Body
+		}
+		sb := &strings.Builder{}
+		chap := &Chapter{Level: baseLevel}
+		for _, e := range body {
+			if list, ok := e.(*List); ok {
+				for _, c := range ListToChapters(list, baseLevel+1) {
+					chap.Body = append(chap.Body, c)
+				}
+				continue
+			}
+			sb.WriteString(PlainText(e))
+		}
+		chap.Title = strings.TrimSpace(sb.String())
+		res = append(res, chap)
+	}
+	return res
+}
+
+// ChaptersToList converts chapters into an unordered list of their titles. Sub chapters become nested lists,
+// any other content of the chapters is omitted.
+func ChaptersToList(chapters []*Chapter) *List {
+	list := UnorderedList()
+	for _, chap := range chapters {
+		item := ListItem(Text(chap.Title))
+		var sub []*Chapter
+		for _, e := range chap.Body {
+			if c, ok := e.(*Chapter); ok {
+				sub = append(sub, c)
+			}
+		}
+		if len(sub) > 0 {
+			item.Add(ChaptersToList(sub))
+		}
+		list.Add(item)
+	}
+	return list
+}
+
+// CoalesceInline merges adjacent spans and adjacent bold, italic or underline groups of the same type within all
+// bodies of the tree, e.g. Bold(Text("a")), Bold(Text("b")) becomes Bold(Text("ab")). Line breaks and any other
+// element separate the merged runs. Merged elements are replaced by new ones, so shared elements are not modified.
+func CoalesceInline(d Discriminator) {
+	Walk(d, func(d Discriminator) bool {
+		if body := bodyOf(d); body != nil {
+			*body = coalesce(*body)
+		}
+		return true
+	})
+}
+
+func coalesce(body []Discriminator) []Discriminator {
+	var res []Discriminator
+	for _, e := range body {
+		if len(res) == 0 {
+			res = append(res, e)
+			continue
+		}
+		last := res[len(res)-1]
+		if a, ok := last.(*Span); ok {
+			if b, ok := e.(*Span); ok {
+				res[len(res)-1] = &Span{Value: a.Value + b.Value}
+				continue
+			}
+		}
+		if a, ok := last.(*defaultBody); ok && isFormatting(a) {
+			if b, ok := e.(*defaultBody); ok && a.Type() == b.Type() {
+				res[len(res)-1] = &defaultBody{name: a.name, Body: append(append([]Discriminator(nil), a.Body...), b.Body...)}
+				continue
+			}
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// isFormatting returns true for the inline groups, which only change the appearance of their body
+func isFormatting(g *defaultBody) bool {
+	switch g.Type() {
+	case BoldType, ItalicType, UnderlineType:
+		return true
+	default:
+		return false
+	}
+}
+
+// bodyOf returns the modifiable body of the element or nil
+func bodyOf(d Discriminator) *[]Discriminator {
+	switch t := d.(type) {
+	case *Document:
+		return &t.Body
+	case *Part:
+		return &t.Body
+	case *Chapter:
+		return &t.Body
+	case *defaultBody:
+		return &t.Body
+	case *Align:
+		return &t.Body
+	case *ListEntry:
+		return &t.Body
+	case *MarginNote:
+		return &t.Body
+	case *Hyperlink:
+		return &t.Body
+	case *Tooltip:
+		return &t.Body
+	case *TableCell:
+		return &t.Body
+	case *Titlepage:
+		return &t.Body
+	default:
+		return nil
+	}
+}
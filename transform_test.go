@@ -0,0 +1,164 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromoteFirstHeading(t *testing.T) {
+	doc := &Document{}
+	h1 := doc.NewChapter("Title")
+	h1.Text("intro")
+	h1.NewChapter("Section").NewChapter("Subsection")
+	doc.Add(Text("trailing"))
+
+	if !doc.PromoteFirstHeading() {
+		t.Fatal("expected promotion")
+	}
+	if doc.Title != "Title" || len(doc.Body) != 3 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	section := doc.Body[1].(*Chapter)
+	if section.Title != "Section" || section.Level != 0 || section.Body[0].(*Chapter).Level != 1 {
+		t.Fatalf("levels not shifted: %+v", section)
+	}
+	if PlainText(doc.Body[2]) != "trailing" {
+		t.Fatal("trailing content lost")
+	}
+
+	multi := &Document{}
+	multi.NewChapter("first")
+	multi.NewChapter("second")
+	if multi.PromoteFirstHeading() || multi.Title != "" || len(multi.Body) != 2 {
+		t.Fatal("documents with multiple top level chapters must not be changed")
+	}
+
+	titled := &Document{Title: "given"}
+	titled.NewChapter("first")
+	if titled.PromoteFirstHeading() || titled.Title != "given" {
+		t.Fatal("documents with a title must not be changed")
+	}
+}
+
+func TestNormalizeLevels(t *testing.T) {
+	doc := &Document{Id: "doc"}
+	top := &Chapter{Title: "top", Level: 0}
+	gap := &Chapter{Title: "gap", Level: 2}
+	deeper := &Chapter{Title: "deeper", Level: 5}
+	sibling := &Chapter{Title: "sibling", Level: 1}
+	gap.Add(deeper)
+	top.Add(gap)
+	doc.Add(top, &Chapter{Title: "flat", Level: 3}, sibling)
+
+	errs := CheckLevels(doc)
+	if len(errs) != 3 || errs[0].Error() != "doc/top/gap: warning: level 2 has a gap, expected 1" {
+		t.Fatalf("unexpected %v", errs)
+	}
+
+	doc.NormalizeLevels()
+	if top.Level != 0 || gap.Level != 1 || deeper.Level != 2 || sibling.Level != 1 || doc.Body[1].(*Chapter).Level != 1 {
+		t.Fatalf("unexpected levels %d %d %d %d %d", top.Level, gap.Level, deeper.Level, doc.Body[1].(*Chapter).Level, sibling.Level)
+	}
+	if len(top.Body) != 1 || top.Body[0] != gap || len(doc.Body) != 3 {
+		t.Fatal("the tree must not change")
+	}
+	if errs := CheckLevels(doc); len(errs) != 0 {
+		t.Fatalf("unexpected %v", errs)
+	}
+}
+
+func TestSortResources(t *testing.T) {
+	ws := &Workspace{}
+	b := ws.NewDocument()
+	b.Id, b.Title = "1", "beta"
+	other := &Chapter{Title: "not a document"}
+	ws.Resources = append(ws.Resources, other)
+	a := ws.NewDocument()
+	a.Id, a.Title = "2", "alpha"
+
+	ws.SortResources("title")
+	if ws.Resources[0] != a || ws.Resources[1] != other || ws.Resources[2] != b {
+		t.Fatalf("expected alpha, the chapter and beta but got %v", ws.Resources)
+	}
+	ws.SortResources("id")
+	if ws.Resources[0] != b || ws.Resources[1] != other || ws.Resources[2] != a {
+		t.Fatalf("expected the documents by id but got %v", ws.Resources)
+	}
+	ws.SortResources("unknown")
+	if ws.Resources[0] != b {
+		t.Fatal("expected an unknown criterion to be ignored")
+	}
+}
+
+func TestListToChapters(t *testing.T) {
+	list := UnorderedList(
+		ListItem(Text("intro"), UnorderedList(ListItem(Text("goals")), Text("scope"))),
+		Text("usage"),
+		UnorderedList(ListItem(Bold(Text("api")))),
+	)
+	chapters := ListToChapters(list, 1)
+	doc := &Document{}
+	for _, chap := range chapters {
+		doc.Add(chap)
+	}
+	want := []string{"1:intro", "2:goals", "2:scope", "1:usage", "2:api"}
+	if got := levelsOf(doc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+
+	back := ListToChapters(ChaptersToList(chapters), 1)
+	if !reflect.DeepEqual(back, chapters) {
+		t.Fatalf("expected %v but got %v", chapters, back)
+	}
+	if got := ChaptersToList(back); !reflect.DeepEqual(got, ChaptersToList(chapters)) {
+		t.Fatalf("unstable conversion %s", debugJson(got.toJson()))
+	}
+}
+
+// levelsOf returns the levels and titles of all chapters in pre-order
+func levelsOf(root Discriminator) []string {
+	var res []string
+	for _, e := range Collect(root, ChapterType) {
+		chap := e.(*Chapter)
+		res = append(res, fmt.Sprintf("%d:%s", chap.Level, chap.Title))
+	}
+	return res
+}
+
+func TestCoalesceInline(t *testing.T) {
+	shared := Text("c")
+	chap := &Chapter{Title: "chap"}
+	chap.Add(Bold(Text("a")), Bold(Text("b"), shared), Italic(Text("i")), Text("x"), Text("y"), LineBreak(), Text("z"))
+	CoalesceInline(&Document{Body: []Discriminator{chap}})
+
+	want := []Discriminator{Bold(Text("abc")), Italic(Text("i")), Text("xy"), LineBreak(), Text("z")}
+	if !reflect.DeepEqual(chap.Body, want) {
+		t.Fatalf("expected %s but got %s", debugJson(toJson(want)), debugJson(toJson(chap.Body)))
+	}
+	if shared.Value != "c" {
+		t.Fatal("shared elements must not be modified")
+	}
+
+	html := renderHTML(chap)
+	if !strings.Contains(html, "<strong>abc</strong>") {
+		t.Fatal(html)
+	}
+}
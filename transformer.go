@@ -17,7 +17,9 @@
 package wdydoc
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	html "html/template"
 	"io"
@@ -26,13 +28,19 @@ import (
 	"path/filepath"
 	"strings"
 	text "text/template"
+	"unicode"
 )
 
+// ErrUnstableRoundTrip is returned by FuzzRoundTrip, if a decoded model does not survive a marshal/unmarshal cycle.
+var ErrUnstableRoundTrip = errors.New("unstable round trip")
+
 // A File maps between an original src file and
 type File struct {
 	parent      *Template
+	root        string // root is the template dir, which contains the srcFile
 	srcFile     string
 	dstFilename string
+	name        *text.Template // name is the template of the dstFilename, if the file name contains an action
 	transformer Transformer
 }
 
@@ -40,11 +48,19 @@ func NewFile(parent *Template, fname string) (*File, error) {
 	f := &File{}
 	f.srcFile = fname
 	f.parent = parent
+	f.root = parent.dir
 	basePath := filepath.Base(fname)
 	ext := filepath.Ext(basePath)
+	f.dstFilename = templateDstName(basePath)
+	if strings.Contains(f.dstFilename, "{{") {
+		tpl, err := parent.text.New("filename:" + f.srcFile).Parse(f.dstFilename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file name template %s: %w", f.srcFile, err)
+		}
+		f.name = tpl
+	}
 	switch strings.ToLower(ext) {
 	case htmlTemplate:
-		f.dstFilename = basePath[:len(basePath)-len(htmlTemplate)]
 		tpl, err := parent.html.New(basePath).ParseFiles(f.srcFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse html template %s: %w", f.srcFile, err)
@@ -54,7 +70,6 @@ func NewFile(parent *Template, fname string) (*File, error) {
 			Template: tpl,
 		}
 	case textTemplate:
-		f.dstFilename = basePath[:len(basePath)-len(textTemplate)]
 		tpl, err := parent.text.New(basePath).ParseFiles(f.srcFile)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse text template %s: %w", f.srcFile, err)
@@ -64,27 +79,152 @@ func NewFile(parent *Template, fname string) (*File, error) {
 			Template: tpl,
 		}
 	default:
-		f.dstFilename = basePath
 		f.transformer = &CopyTransformer{SrcFilename: f.srcFile}
 	}
 	return f, nil
 }
 
+// templateDstName returns the file name without the template extension. Only the last extension is removed, so
+// the output type is declared by a double extension, e.g. report.tex.tmpl becomes report.tex and a.b.tmpl becomes
+// a.b. Names without a double extension result in an extensionless file, e.g. latexmkrc.tmpl becomes latexmkrc.
+// Files without a template extension keep their name.
+func templateDstName(basePath string) string {
+	ext := filepath.Ext(basePath)
+	switch strings.ToLower(ext) {
+	case htmlTemplate, textTemplate:
+		return basePath[:len(basePath)-len(ext)]
+	default:
+		return basePath
+	}
+}
+
+// dstFile returns the path of the generated file within the build dir
+func (f *File) dstFile() string {
+	relativePath := f.srcFile[len(f.root):]
+	return filepath.Join(f.parent.buildDir, filepath.Dir(relativePath), f.dstFilename)
+}
+
+// isWithinDir returns true, if the path is the dir or is located somewhere below it
+func isWithinDir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// isUpToDate returns true, if the file is just copied and its copy in the build dir has the same size and
+// modification time as the source, see Template.Incremental.
+func (f *File) isUpToDate() bool {
+	if _, isCopy := f.transformer.(*CopyTransformer); !isCopy {
+		return false
+	}
+	src, err := os.Stat(f.srcFile)
+	if err != nil {
+		return false
+	}
+	dst, err := os.Stat(f.dstFile())
+	if err != nil {
+		return false
+	}
+	return src.Size() == dst.Size() && src.ModTime().Equal(dst.ModTime())
+}
+
+// resolveName evaluates the file name template against the model, e.g. manual-{{.Title}}.pdf.tmpl. The result is
+// sanitized to a single safe file name.
+func (f *File) resolveName(model interface{}) error {
+	if f.name == nil {
+		return nil
+	}
+	sb := &strings.Builder{}
+	if err := f.name.Execute(sb, model); err != nil {
+		return fmt.Errorf("failed to apply file name template for %s: %w", f.srcFile, err)
+	}
+	name := safeFilename(sb.String())
+	if name == "" || name != filepath.Base(name) {
+		return fmt.Errorf("file name template of %s results in an invalid name '%s'", f.srcFile, name)
+	}
+	f.dstFilename = name
+	return nil
+}
+
+// safeFilename replaces path separators, white spaces and any other rune, which is not a letter, a digit, a dot,
+// a dash or an underscore by a single dash. Leading and trailing dots and dashes are removed.
+func safeFilename(name string) string {
+	sb := &strings.Builder{}
+	dash := false
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' {
+			sb.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash {
+			sb.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.Trim(sb.String(), ".-")
+}
+
 func (f *File) Apply(model interface{}) error {
-	relativePath := f.srcFile[len(f.parent.dir):]
-	dstFile := filepath.Join(f.parent.buildDir, filepath.Dir(relativePath), f.dstFilename)
-	_ = os.MkdirAll(filepath.Dir(dstFile), os.ModePerm)
-	out, err := os.OpenFile(dstFile, os.O_CREATE|os.O_RDWR, os.ModePerm)
+	if err := f.resolveName(model); err != nil {
+		return err
+	}
+	dstFile := f.dstFile()
+	if !isWithinDir(f.parent.buildDir, dstFile) {
+		return fmt.Errorf("output file %s of %s is outside of the build dir", dstFile, f.srcFile)
+	}
+	_ = os.MkdirAll(filepath.Dir(dstFile), f.parent.dirMode())
+	out, err := os.OpenFile(dstFile, os.O_CREATE|os.O_TRUNC|os.O_RDWR, f.parent.fileMode())
 	if err != nil {
 		return fmt.Errorf("unable to create file %s: %w", dstFile, err)
 	}
+	if err := out.Chmod(f.parent.fileMode()); err != nil {
+		return fmt.Errorf("unable to change mode of %s: %w", dstFile, err)
+	}
 	defer func() {
 		err := out.Close()
 		if err != nil {
 			fmt.Printf("failed to close %s: %v", dstFile, err)
 		}
 	}()
-	return f.transformer.Transform(model, out)
+
+	_, isCopy := f.transformer.(*CopyTransformer)
+	if isCopy || f.parent.LineEnding == "" && !f.parent.EnsureFinalNewline && !f.parent.EmitSourceMap {
+		return f.transformer.Transform(model, out)
+	}
+	f.parent.fragments = nil
+	buf := &bytes.Buffer{}
+	if err := f.transformer.Transform(model, buf); err != nil {
+		return err
+	}
+	if f.parent.EmitSourceMap {
+		if err := f.parent.writeSourceMap(dstFile, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if _, err := out.Write(normalizeLines(buf.Bytes(), f.parent.LineEnding, f.parent.EnsureFinalNewline)); err != nil {
+		return fmt.Errorf("unable to write file %s: %w", dstFile, err)
+	}
+	return nil
+}
+
+// normalizeLines converts all line endings to either lf or crlf. Any other value keeps them untouched.
+// If finalNewline is set, a missing line ending is appended.
+func normalizeLines(b []byte, lineEnding string, finalNewline bool) []byte {
+	nl := []byte("\n")
+	switch lineEnding {
+	case LineEndingLF:
+		b = bytes.ReplaceAll(b, []byte("\r\n"), nl)
+	case LineEndingCRLF:
+		nl = []byte("\r\n")
+		b = bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n")), []byte("\n"), nl)
+	}
+	if finalNewline && len(b) > 0 && b[len(b)-1] != '\n' {
+		b = append(b, nl...)
+	}
+	return b
 }
 
 // A Transformer takes the model as input and a writer as output and applies a content transformation on it.
@@ -150,6 +290,9 @@ func Unmarshal(b []byte) (*Workspace, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkMarkup(tmp); err != nil {
+		return nil, err
+	}
 	w := &Workspace{}
 	w.fromJson(tmp)
 	return w, nil
@@ -161,5 +304,76 @@ func UnmarshalFile(fname string) (*Workspace, error) {
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse %s: %w", fname, err)
 	}
-	return Unmarshal(b)
+	w, err := Unmarshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", fname, err)
+	}
+	return w, nil
+}
+
+// UnmarshalYAML decodes yaml markup, which has the same structure as the json markup
+func UnmarshalYAML(b []byte) (*Workspace, error) {
+	v, err := decodeYAML(b)
+	if err != nil {
+		return nil, err
+	}
+	tmp, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("yaml: expected a mapping at the top level but found %T", v)
+	}
+	if err := checkMarkup(tmp); err != nil {
+		return nil, err
+	}
+	w := &Workspace{}
+	w.fromJson(tmp)
+	return w, nil
+}
+
+// UnmarshalYAMLFile decodes a yaml markup file
+func UnmarshalYAMLFile(fname string) (*Workspace, error) {
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", fname, err)
+	}
+	w, err := UnmarshalYAML(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", fname, err)
+	}
+	return w, nil
+}
+
+// unmarshalInputFile decodes a markup file as yaml, if it has a .yaml or .yml extension and as json otherwise
+func unmarshalInputFile(fname string) (*Workspace, error) {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".yaml", ".yml":
+		return UnmarshalYAMLFile(fname)
+	default:
+		return UnmarshalFile(fname)
+	}
+}
+
+// FuzzRoundTrip unmarshals arbitrary data and, if that succeeds, marshals and unmarshals the result again. The
+// second encoding must be identical to the first one, otherwise the model is not stable and an error is returned.
+// Invalid input must only cause an error but never a panic, so this is usable as a fuzz target.
+func FuzzRoundTrip(data []byte) error {
+	w, err := Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	first, err := Marshal(w)
+	if err != nil {
+		return fmt.Errorf("%w: cannot marshal: %v", ErrUnstableRoundTrip, err)
+	}
+	w, err = Unmarshal(first)
+	if err != nil {
+		return fmt.Errorf("%w: cannot unmarshal own output: %v", ErrUnstableRoundTrip, err)
+	}
+	second, err := Marshal(w)
+	if err != nil {
+		return fmt.Errorf("%w: cannot marshal again: %v", ErrUnstableRoundTrip, err)
+	}
+	if !bytes.Equal(first, second) {
+		return fmt.Errorf("%w: %s != %s", ErrUnstableRoundTrip, string(first), string(second))
+	}
+	return nil
 }
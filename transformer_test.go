@@ -0,0 +1,163 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func FuzzRoundTripJson(f *testing.F) {
+	example, err := ioutil.ReadFile("example.json")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(example)
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{"title":1,"version":true,"format":"x"}`))
+	f.Add([]byte(`{"resources":[{"type":"document","authors":[{"type":"chapter"},{"type":"author","email":3}]}]}`))
+	f.Add([]byte(`{"resources":[{"type":"document","body":[{"type":"chapter","level":1.5}]}]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		err := FuzzRoundTrip(data)
+		if errors.Is(err, ErrUnstableRoundTrip) {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestTemplateDstName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.tex.tmpl", "report.tex"},
+		{"a.b.tmpl", "a.b"},
+		{"a.b.c.TMPL", "a.b.c"},
+		{"index.html.gohtml", "index.html"},
+		{"report.tmpl", "report"},
+		{"latexmkrc", "latexmkrc"},
+		{"style.css", "style.css"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := templateDstName(tt.name); got != tt.want {
+				t.Errorf("templateDstName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFileDstName(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.b.tmpl": "x", "report.tmpl": "y"})
+	tpl, err := ReadTemplate(dir, filepath.Join(t.TempDir(), "build"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, f := range tpl.files {
+		names[f.dstFilename] = true
+	}
+	if !names["a.b"] || !names["report"] || len(names) != 2 {
+		t.Fatalf("unexpected destination names: %v", names)
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.txt.tmpl": "a\nb\r\nc", "b.html.gohtml": "<p>\n{{.Title}}</p>", "c.txt": "x\ny"})
+	buildDir := filepath.Join(t.TempDir(), "build")
+	tpl, err := ReadTemplate(dir, buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.LineEnding = LineEndingCRLF
+	tpl.EnsureFinalNewline = true
+	if _, err := tpl.Build(&Workspace{Title: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ReadFiles(buildDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a.txt": "a\r\nb\r\nc\r\n", "b.html": "<p>\r\nt</p>\r\n", "c.txt": "x\ny"}
+	for name, content := range want {
+		if string(files[name]) != content {
+			t.Fatalf("%s: expected %q but got %q", name, content, string(files[name]))
+		}
+	}
+}
+
+func TestNormalizeLines(t *testing.T) {
+	tests := []struct {
+		in           string
+		lineEnding   string
+		finalNewline bool
+		want         string
+	}{
+		{"a\r\nb\n", "", false, "a\r\nb\n"},
+		{"a\r\nb", LineEndingLF, false, "a\nb"},
+		{"a\r\nb\nc", LineEndingCRLF, false, "a\r\nb\r\nc"},
+		{"a", "", true, "a\n"},
+		{"a\n", "", true, "a\n"},
+		{"a", LineEndingCRLF, true, "a\r\n"},
+		{"", LineEndingCRLF, true, ""},
+	}
+	for _, tt := range tests {
+		if got := string(normalizeLines([]byte(tt.in), tt.lineEnding, tt.finalNewline)); got != tt.want {
+			t.Errorf("normalizeLines(%q, %s, %v) = %q, want %q", tt.in, tt.lineEnding, tt.finalNewline, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalUnknownType(t *testing.T) {
+	markup := `{"type":"workspace","resources":[{"type":"document","id":"doc","body":[
+		{"type":"chapter","title":"intro","body":[{"type":"bogus"}]}]}]}`
+	_, err := Unmarshal([]byte(markup))
+	if err == nil {
+		t.Fatal("expected an error for the unknown type")
+	}
+	if !strings.Contains(err.Error(), "unknown type 'bogus'") || !strings.Contains(err.Error(), "intro") {
+		t.Fatalf("expected the type and its location but got %v", err)
+	}
+	if err := FuzzRoundTrip([]byte(`{"type":"workspace","resources":[{"type":"bogus"}]}`)); err == nil {
+		t.Fatal("expected an error but got nil")
+	}
+}
+
+func TestUnmarshalRejectedTypes(t *testing.T) {
+	tests := []struct {
+		markup string
+		want   string
+	}{
+		{`{"type":"workspace","resources":[{"type":"document","id":"doc","body":[{"type":"table","rows":[{"type":"bogus"}]}]}]}`,
+			"doc: unknown type 'bogus'"},
+		{`{"type":"workspace","resources":[{"type":"document","id":"doc","body":[{"type":"table","header":[{"type":"tablerow","cells":[{"type":"text","value":"a"}]}]}]}]}`,
+			"doc: unexpected type 'text', expected 'tablecell'"},
+		{`{"type":"workspace","resources":[{"type":"document","id":"doc","authors":[{"type":"bogus"}]}]}`,
+			"doc: unknown type 'bogus'"},
+		{`{"type":"workspace","defaultAuthors":[{"type":"chapter"}]}`,
+			"unexpected type 'chapter', expected 'author'"},
+	}
+	for _, tt := range tests {
+		_, err := Unmarshal([]byte(tt.markup))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: expected error containing '%s' but got %v", tt.markup, tt.want, err)
+		}
+	}
+}
@@ -23,7 +23,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 const typeAttrName = "type"
@@ -31,7 +35,12 @@ const WorkspaceType = "workspace"
 const DocumentType = "document"
 const ChapterType = "chapter"
 const AuthorType = "author"
-const NewlineType = "newline"
+const LineBreakType = "newline"
+const ParagraphBreakType = "paragraph"
+
+// Deprecated: NewlineType is an alias of LineBreakType
+const NewlineType = LineBreakType
+
 const NewpageType = "newpage"
 const ItalicType = "italic"
 const BoldType = "bold"
@@ -39,8 +48,25 @@ const UnderlineType = "underline"
 const CodeType = "code"
 const ImageType = "image"
 const TOCType = "toc"
+const AppendixType = "appendix"
 const TitlepageType = "titlepage"
 const TextType = "text"
+const ListType = "list"
+const ListItemType = "listitem"
+const DiffType = "diff"
+const VarRefType = "var"
+const AlignType = "align"
+const MarginNoteType = "marginnote"
+const AttachmentType = "attachment"
+const PartType = "part"
+const EmbedType = "embed"
+const AbbreviationType = "abbr"
+const RevisionHistoryType = "revisions"
+const TableType = "table"
+const LinkType = "link"
+const TooltipType = "tooltip"
+const TableRowType = "tablerow"
+const TableCellType = "tablecell"
 
 func assertObjList(v interface{}) []map[string]interface{} {
 	var res []map[string]interface{}
@@ -68,7 +94,13 @@ func toJson(genericSlice interface{}) []interface{} {
 }
 
 func fromJson(m map[string]interface{}) Discriminator {
-	typeName := optString(m, typeAttrName)
+	obj := newElement(optString(m, typeAttrName))
+	obj.fromJson(m)
+	return obj
+}
+
+// newElement creates an empty element of the type
+func newElement(typeName string) Discriminator {
 	var obj Discriminator
 	switch typeName {
 	case WorkspaceType:
@@ -83,8 +115,12 @@ func fromJson(m map[string]interface{}) Discriminator {
 		obj = &Span{}
 	case TOCType:
 		obj = TOC()
-	case NewlineType:
-		obj = Newline()
+	case AppendixType:
+		obj = Appendix()
+	case LineBreakType:
+		obj = LineBreak()
+	case ParagraphBreakType:
+		obj = ParagraphBreak()
 	case ItalicType:
 		obj = Italic()
 	case BoldType:
@@ -99,12 +135,131 @@ func fromJson(m map[string]interface{}) Discriminator {
 		obj = TitlePage()
 	case NewpageType:
 		obj = Newpage()
+	case ListType:
+		obj = &List{}
+	case ListItemType:
+		obj = &ListEntry{}
+	case DiffType:
+		obj = &Diff{}
+	case VarRefType:
+		obj = &VarRef{}
+	case AlignType:
+		obj = &Align{}
+	case MarginNoteType:
+		obj = &MarginNote{}
+	case AttachmentType:
+		obj = &Attachment{}
+	case PartType:
+		obj = &Part{}
+	case EmbedType:
+		obj = &Embed{}
+	case AbbreviationType:
+		obj = &Abbreviation{}
+	case RevisionHistoryType:
+		obj = &RevisionHistory{}
+	case TableType:
+		obj = &Table{}
+	case LinkType:
+		obj = &Hyperlink{}
+	case TooltipType:
+		obj = &Tooltip{}
+	case TableRowType:
+		obj = &TableRow{}
+	case TableCellType:
+		obj = &TableCell{}
 	default:
-		panic("unknown format type: " + typeName + " -> " + debugJson(m))
+		// reported by checkMarkup, so that invalid markup cannot crash the caller
+		obj = &unknownElement{}
 	}
-	obj.fromJson(m)
 	return obj
 }
+
+// An unknownElement keeps the markup of an element with an unknown type.
+type unknownElement struct {
+	m map[string]interface{}
+}
+
+func (u *unknownElement) Type() string {
+	typeName, _ := u.m[typeAttrName].(string)
+	return typeName
+}
+
+func (u *unknownElement) toJson() map[string]interface{} {
+	return u.m
+}
+
+func (u *unknownElement) fromJson(m map[string]interface{}) {
+	u.m = m
+}
+
+// elementAttrs are the attributes, which contain elements. The elements of some attributes must have a certain
+// type, the others may have any known type.
+var elementAttrs = map[string]string{
+	"resources":      "",
+	"body":           "",
+	"items":          "",
+	"authors":        AuthorType,
+	"defaultAuthors": AuthorType,
+	"header":         TableRowType,
+	"rows":           TableRowType,
+	"cells":          TableCellType,
+}
+
+// checkMarkup inspects the decoded markup of a workspace, before it is converted by fromJson. It returns an error,
+// which describes each element of an unknown type and each element, which is not allowed at its position, like a
+// chapter within the authors of a document, together with its location.
+func checkMarkup(workspace map[string]interface{}) error {
+	var errs []error
+	var path []string
+	var visit func(m map[string]interface{}, want string)
+	visitAttrs := func(m map[string]interface{}) {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			if _, ok := elementAttrs[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			for _, obj := range assertObjList(m[key]) {
+				visit(obj, elementAttrs[key])
+			}
+		}
+	}
+	visit = func(m map[string]interface{}, want string) {
+		typeName := optString(m, typeAttrName)
+		if _, unknown := newElement(typeName).(*unknownElement); unknown {
+			errs = append(errs, &ValidationError{Path: strings.Join(path, "/"), Message: fmt.Sprintf("unknown type '%s'", typeName)})
+			return
+		}
+		if want != "" && typeName != want {
+			errs = append(errs, &ValidationError{Path: strings.Join(path, "/"), Message: fmt.Sprintf("unexpected type '%s', expected '%s'", typeName, want)})
+			return
+		}
+		name := ""
+		switch typeName {
+		case DocumentType:
+			name = optString(m, "id")
+			if name == "" {
+				name = optString(m, "title")
+			}
+		case PartType, ChapterType:
+			name = optString(m, "title")
+		}
+		if name != "" {
+			path = append(path, name)
+			defer func() {
+				path = path[:len(path)-1]
+			}()
+		}
+		visitAttrs(m)
+	}
+	visitAttrs(workspace)
+	if len(errs) > 0 {
+		return joinErrors("invalid markup:", errs)
+	}
+	return nil
+}
 func optString(m map[string]interface{}, key string) string {
 	if str, ok := m[key].(string); ok {
 		return str
@@ -112,13 +267,73 @@ func optString(m map[string]interface{}, key string) string {
 	return ""
 }
 
+// optStringSlice returns the strings of the slice, either as created by toJson or as decoded by encoding/json.
+// Elements which are not strings are skipped.
 func optStringSlice(m map[string]interface{}, key string) []string {
-	if str, ok := m[key].([]string); ok {
-		return str
+	switch t := m[key].(type) {
+	case []string:
+		return t
+	case []interface{}:
+		res := make([]string, 0, len(t))
+		for _, v := range t {
+			if str, ok := v.(string); ok {
+				res = append(res, str)
+			}
+		}
+		return res
+	default:
+		return nil
+	}
+}
+
+func optStringMap(m map[string]interface{}, key string) map[string]string {
+	if obj, ok := m[key].(map[string]interface{}); ok {
+		res := make(map[string]string)
+		for k, v := range obj {
+			if str, ok := v.(string); ok {
+				res[k] = str
+			}
+		}
+		return res
+	}
+	if obj, ok := m[key].(map[string]string); ok {
+		return obj
 	}
 	return nil
 }
 
+// optIntMap reads an object with integer keys, like {"3": "note"}. Keys which are not integers are ignored.
+func optIntMap(m map[string]interface{}, key string) map[int]string {
+	var res map[int]string
+	for k, v := range optStringMap(m, key) {
+		i, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		if res == nil {
+			res = make(map[int]string)
+		}
+		res[i] = v
+	}
+	return res
+}
+
+// intMap is the inverse of optIntMap
+func intMap(m map[int]string) map[string]string {
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[strconv.Itoa(k)] = v
+	}
+	return res
+}
+
+func optBool(m map[string]interface{}, key string) bool {
+	if b, ok := m[key].(bool); ok {
+		return b
+	}
+	return false
+}
+
 func optInt(m map[string]interface{}, key string) int {
 	if i, ok := m[key].(int); ok {
 		return i
@@ -129,6 +344,27 @@ func optInt(m map[string]interface{}, key string) int {
 	if i, ok := m[key].(float64); ok {
 		return int(i)
 	}
+	if str, ok := m[key].(string); ok {
+		if i, err := strconv.Atoi(strings.TrimSpace(str)); err == nil {
+			return i
+		}
+	}
+	return 0
+}
+
+func optFloat(m map[string]interface{}, key string) float64 {
+	switch v := m[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f
+		}
+	}
 	return 0
 }
 
@@ -159,6 +395,10 @@ func (d *defaultBody) Type() string {
 	return d.name
 }
 
+func (d *defaultBody) children() []Discriminator {
+	return d.Body
+}
+
 func (d *defaultBody) toJson() map[string]interface{} {
 	m := make(map[string]interface{})
 	m[typeAttrName] = d.Type()
@@ -186,6 +426,16 @@ func optSet(m map[string]interface{}, key string, val interface{}) {
 	}
 }
 
+// redact replaces all secret values within str
+func redact(str string, secrets map[string]string) string {
+	for _, v := range secrets {
+		if v != "" {
+			str = strings.ReplaceAll(str, v, "***")
+		}
+	}
+	return str
+}
+
 func debugJson(i interface{}) string {
 	if i == nil {
 		return "nil"
@@ -197,8 +447,14 @@ func debugJson(i interface{}) string {
 	return string(b)
 }
 
-// CopyDir copies a whole directory recursively
+// CopyDir copies a whole directory recursively and preserves the file modes
 func CopyDir(src string, dst string) error {
+	return CopyDirMode(src, dst, 0, 0)
+}
+
+// CopyDirMode copies a whole directory recursively and applies the given modes to the created files and
+// directories. A zero mode preserves the mode of the source.
+func CopyDirMode(src string, dst string, fileMode os.FileMode, dirMode os.FileMode) error {
 	var err error
 	var fds []os.FileInfo
 	var srcinfo os.FileInfo
@@ -207,7 +463,14 @@ func CopyDir(src string, dst string) error {
 		return err
 	}
 
-	if err = os.MkdirAll(dst, srcinfo.Mode()); err != nil {
+	mode := dirMode
+	if mode == 0 {
+		mode = srcinfo.Mode()
+	}
+	if err = os.MkdirAll(dst, mode); err != nil {
+		return err
+	}
+	if err = os.Chmod(dst, mode); err != nil {
 		return err
 	}
 
@@ -219,11 +482,11 @@ func CopyDir(src string, dst string) error {
 		dstfp := path.Join(dst, fd.Name())
 
 		if fd.IsDir() {
-			if err = CopyDir(srcfp, dstfp); err != nil {
+			if err = CopyDirMode(srcfp, dstfp, fileMode, dirMode); err != nil {
 				fmt.Println(err)
 			}
 		} else {
-			if err = CopyFile(srcfp, dstfp); err != nil {
+			if err = CopyFileMode(srcfp, dstfp, fileMode); err != nil {
 				fmt.Println(err)
 			}
 		}
@@ -231,8 +494,14 @@ func CopyDir(src string, dst string) error {
 	return nil
 }
 
-// CopyFile copies a single file from src to dst
+// CopyFile copies a single file from src to dst and preserves the file mode
 func CopyFile(src, dst string) error {
+	return CopyFileMode(src, dst, 0)
+}
+
+// CopyFileMode copies a single file from src to dst and applies the given mode. A zero mode preserves the mode of
+// the source.
+func CopyFileMode(src, dst string, mode os.FileMode) error {
 	var err error
 	var srcfd *os.File
 	var dstfd *os.File
@@ -251,10 +520,41 @@ func CopyFile(src, dst string) error {
 	if _, err = io.Copy(dstfd, srcfd); err != nil {
 		return err
 	}
-	if srcinfo, err = os.Stat(src); err != nil {
-		return err
+	if mode == 0 {
+		if srcinfo, err = os.Stat(src); err != nil {
+			return err
+		}
+		mode = srcinfo.Mode()
+	}
+	return os.Chmod(dst, mode)
+}
+
+// ReadFiles reads all files of the directory recursively and returns their contents by the slash separated path
+// relative to dir.
+func ReadFiles(dir string) (map[string][]byte, error) {
+	res := make(map[string][]byte)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk path %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %w", path, err)
+		}
+		res[filepath.ToSlash(rel)] = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return os.Chmod(dst, srcinfo.Mode())
+	return res, nil
 }
 
 func IsDir(p string) bool {
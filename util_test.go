@@ -0,0 +1,76 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptStringMap(t *testing.T) {
+	m := map[string]interface{}{
+		"links": map[string]interface{}{"web": "https://example.com", "count": 3},
+		"typed": map[string]string{"a": "b"},
+		"wrong": "no map",
+	}
+	tests := []struct {
+		key  string
+		want map[string]string
+	}{
+		{"missing", nil},
+		{"wrong", nil},
+		{"links", map[string]string{"web": "https://example.com"}},
+		{"typed", map[string]string{"a": "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := optStringMap(m, tt.key); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("optStringMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOptFloat(t *testing.T) {
+	m := map[string]interface{}{
+		"float":   0.75,
+		"int":     2,
+		"int64":   int64(3),
+		"string":  " 1.5 ",
+		"invalid": "abc",
+		"wrong":   true,
+	}
+	tests := []struct {
+		key  string
+		want float64
+	}{
+		{"missing", 0},
+		{"wrong", 0},
+		{"invalid", 0},
+		{"float", 0.75},
+		{"int", 2},
+		{"int64", 3},
+		{"string", 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := optFloat(m, tt.key); got != tt.want {
+				t.Errorf("optFloat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A ValidationError describes a problem of an element at a certain location of the tree.
+type ValidationError struct {
+	Path    string // Path of the element, made of document ids and chapter titles
+	Message string // Message describes the actual problem
+	Warning bool   // Warning is set for problems, which do not break the output
+}
+
+func (e *ValidationError) Error() string {
+	msg := e.Message
+	if e.Warning {
+		msg = "warning: " + msg
+	}
+	if e.Path == "" {
+		return msg
+	}
+	return e.Path + ": " + msg
+}
+
+// Validate checks the workspace for problems, which would otherwise only be discovered in the generated output.
+// Local image files are resolved relative to the asset dir, which is not checked if empty.
+func (w *Workspace) Validate(assetDir string) []error {
+	var res []error
+	if assetDir != "" {
+		res = append(res, CheckImages(w, assetDir)...)
+	}
+	res = append(res, ResolveVariables(w, w.Variables)...)
+	res = append(res, CheckCodeSizes(w)...)
+	res = append(res, CheckPlacements(w)...)
+	res = append(res, CheckLevels(w)...)
+	res = append(res, w.CheckReferences()...)
+	return res
+}
+
+// A reference points to the id of another element, see CheckReferences.
+type reference interface {
+	// referenceTarget returns the referenced id or an empty string, if the element does not point to any id
+	referenceTarget() string
+}
+
+// CheckReferences returns an error for each reference, whose id is neither defined by a document nor by a listing.
+// Listings, which are never referenced, are reported as warnings. Unreferenced documents are fine, because their
+// ids are also used to select them for a BuildRule.
+func (w *Workspace) CheckReferences() []error {
+	defined := make(map[string]bool)
+	referenced := make(map[string]bool)
+	walkPath(w, func(d Discriminator, path string) {
+		switch t := d.(type) {
+		case *Document:
+			defined[t.Id] = t.Id != ""
+		case *Code:
+			defined[t.Id] = t.Id != ""
+		case reference:
+			referenced[t.referenceTarget()] = true
+		}
+	})
+
+	var res []error
+	var warnings []error
+	walkPath(w, func(d Discriminator, path string) {
+		switch t := d.(type) {
+		case *Code:
+			if t.Id != "" && !referenced[t.Id] {
+				warnings = append(warnings, &ValidationError{Path: path, Message: fmt.Sprintf("listing '%s' is never referenced", t.Id), Warning: true})
+			}
+		case reference:
+			if target := t.referenceTarget(); target != "" && !defined[target] {
+				res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("dangling reference '#%s'", target)})
+			}
+		}
+	})
+	return append(res, warnings...)
+}
+
+// CheckImages returns an error for each local image, which cannot be found relative to the asset dir.
+// Images with an url are skipped.
+func CheckImages(root Discriminator, assetDir string) []error {
+	var res []error
+	walkPath(root, func(d Discriminator, path string) {
+		img, ok := d.(*Image)
+		if !ok || img.Src == "" || isUrl(img.Src) {
+			return
+		}
+		fname := img.Src
+		if !filepath.IsAbs(fname) {
+			fname = filepath.Join(assetDir, fname)
+		}
+		if _, err := os.Stat(fname); err != nil {
+			res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("missing image '%s'", img.Src)})
+		}
+	})
+	return res
+}
+
+// codeLinesWarning is the amount of lines, from which on a code block without MaxLines is reported
+const codeLinesWarning = 5000
+
+// CheckCodeSizes returns a warning for each code block with more lines than are sensible to render, unless its
+// MaxLines are set.
+func CheckCodeSizes(root Discriminator) []error {
+	var res []error
+	walkPath(root, func(d Discriminator, path string) {
+		code, ok := d.(*Code)
+		if !ok || code.MaxLines > 0 || len(code.Lines) <= codeLinesWarning {
+			return
+		}
+		res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("code block has %d lines, consider to set MaxLines", len(code.Lines)), Warning: true})
+	})
+	return res
+}
+
+// CheckPlacements returns an error for each float placement, which contains other characters than h, t, b, p or !.
+func CheckPlacements(root Discriminator) []error {
+	var res []error
+	walkPath(root, func(d Discriminator, path string) {
+		switch t := d.(type) {
+		case *Image:
+			if !isValidPlacement(t.Placement) {
+				res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("invalid placement '%s' of image '%s'", t.Placement, t.Src)})
+			}
+		case *Table:
+			if !isValidPlacement(t.Placement) {
+				res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("invalid placement '%s' of table '%s'", t.Placement, t.Caption)})
+			}
+		}
+	})
+	return res
+}
+
+func isValidPlacement(placement string) bool {
+	return strings.Trim(placement, "htbp!") == ""
+}
+
+// CheckLevels returns a warning for each chapter, whose level has a gap to its parent or previous sibling. See
+// Document.NormalizeLevels.
+func CheckLevels(root Discriminator) []error {
+	var res []error
+	var docs []*Document
+	switch t := root.(type) {
+	case *Workspace:
+		docs = t.Documents()
+	case *Document:
+		docs = append(docs, t)
+	}
+	for _, doc := range docs {
+		levels := make(map[*Chapter]int)
+		normalizeLevels(doc.Body, 0, func(chap *Chapter, level int) {
+			levels[chap] = level
+		})
+		walkPath(doc, func(d Discriminator, path string) {
+			chap, ok := d.(*Chapter)
+			if !ok {
+				return
+			}
+			if level, ok := levels[chap]; ok && level < chap.Level {
+				res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("level %d has a gap, expected %d", chap.Level, level), Warning: true})
+			}
+		})
+	}
+	return res
+}
+
+// CheckCode returns an error for each code block, which is rejected by the validator registered for its hint.
+func CheckCode(root Discriminator, validators map[string]func(*Code) error) []error {
+	if len(validators) == 0 {
+		return nil
+	}
+	var res []error
+	walkPath(root, func(d Discriminator, path string) {
+		code, ok := d.(*Code)
+		if !ok {
+			return
+		}
+		validator, ok := validators[code.Hint]
+		if !ok {
+			return
+		}
+		if err := validator(code); err != nil {
+			res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("invalid %s code: %v", code.Hint, err)})
+		}
+	})
+	return res
+}
+
+// joinErrors combines multiple errors into a single one, one per line.
+func joinErrors(msg string, errs []error) error {
+	sb := &strings.Builder{}
+	sb.WriteString(msg)
+	for _, err := range errs {
+		sb.WriteString("\n\t")
+		sb.WriteString(err.Error())
+	}
+	return fmt.Errorf("%s", sb.String())
+}
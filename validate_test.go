@@ -0,0 +1,93 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckImages(t *testing.T) {
+	assets := writeFiles(t, map[string]string{"img/logo.png": "png"})
+	ws := &Workspace{}
+	doc := ws.NewDocument()
+	doc.Id = "manual"
+	doc.Add(&Image{Src: "img/logo.png"})
+	chap := doc.NewChapter("intro")
+	chap.Add(&Image{Src: "img/typo.png"}, &Image{Src: "https://example.com/remote.png"})
+	chap.NewChapter("details").Add(&Image{Src: "missing.jpg"})
+
+	errs := ws.Validate(assets)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 missing images but got %v", errs)
+	}
+	if errs[0].Error() != "manual/intro: missing image 'img/typo.png'" {
+		t.Fatal(errs[0])
+	}
+	if errs[1].Error() != "manual/intro/details: missing image 'missing.jpg'" {
+		t.Fatal(errs[1])
+	}
+
+	build, err := NewBuild(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AssetDir = assets
+	build.AddRule(&BuildRule{Id: "manual", Template: writeFiles(t, map[string]string{"a.txt": ""})})
+	err = build.Apply()
+	if err == nil || !strings.Contains(err.Error(), "img/typo.png") || !strings.Contains(err.Error(), "missing.jpg") {
+		t.Fatalf("expected all missing images but got %v", err)
+	}
+}
+
+func TestCheckPlacements(t *testing.T) {
+	img := &Image{Src: "a.png", Placement: "htbp!"}
+	if res := roundTrip(t, img).(*Image); res.Placement != "htbp!" {
+		t.Fatalf("placement not persisted: %+v", res)
+	}
+	if got := renderLatex(img); !strings.HasPrefix(got, "\\begin{figure}[htbp!]\n") {
+		t.Fatal(got)
+	}
+
+	doc := &Document{Id: "doc"}
+	doc.Add(img, &Image{Src: "default.png"}, &Image{Src: "bad.png", Placement: "h]{x"})
+	errs := CheckPlacements(doc)
+	if len(errs) != 1 || errs[0].Error() != "doc: invalid placement 'h]{x' of image 'bad.png'" {
+		t.Fatalf("unexpected %v", errs)
+	}
+}
+
+func TestCheckReferences(t *testing.T) {
+	ws := &Workspace{}
+	doc := ws.NewDocument()
+	doc.Id = "manual"
+	chap := doc.NewChapter("intro")
+	chap.Add(&Code{Id: "main", Lines: []string{"func main() {}"}}, &Code{Id: "unused"})
+	chap.Add(Link("#main", Text("see main")), Link("#manual"), Link("https://example.com"))
+	chap.NewChapter("details").Add(Link("#removed", Text("gone")))
+
+	errs := ws.Validate("")
+	if len(errs) != 2 {
+		t.Fatalf("expected a dangling reference and a warning but got %v", errs)
+	}
+	if errs[0].Error() != "manual/intro/details: dangling reference '#removed'" {
+		t.Fatal(errs[0])
+	}
+	if err, ok := errs[1].(*ValidationError); !ok || !err.Warning || err.Error() != "manual/intro: warning: listing 'unused' is never referenced" {
+		t.Fatal(errs[1])
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"fmt"
+)
+
+// A VarRef is an inline placeholder for a variable of the workspace, like a product name or version. The Value is
+// resolved by ResolveVariables during the build.
+type VarRef struct {
+	Name  string
+	Value string // Value is not serialized but calculated by ResolveVariables
+}
+
+// Var creates a reference to the named variable
+func Var(name string) *VarRef {
+	return &VarRef{Name: name}
+}
+
+func (v *VarRef) String() string {
+	return v.Value
+}
+
+func (v *VarRef) Type() string {
+	return VarRefType
+}
+
+func (v *VarRef) toJson() map[string]interface{} {
+	m := make(map[string]interface{})
+	m[typeAttrName] = v.Type()
+	m["name"] = v.Name
+	return m
+}
+
+func (v *VarRef) fromJson(m map[string]interface{}) {
+	v.Name = optString(m, "name")
+}
+
+// undefinedVariable returns the visible placeholder of an undefined variable
+func undefinedVariable(name string) string {
+	return "[undefined variable " + name + "]"
+}
+
+// ResolveVariables sets the Value of each VarRef within the tree. An undefined variable gets a visible placeholder
+// and is reported by an error.
+func ResolveVariables(root Discriminator, vars map[string]string) []error {
+	var res []error
+	walkPath(root, func(d Discriminator, path string) {
+		ref, ok := d.(*VarRef)
+		if !ok {
+			return
+		}
+		if v, ok := vars[ref.Name]; ok {
+			ref.Value = v
+			return
+		}
+		ref.Value = undefinedVariable(ref.Name)
+		res = append(res, &ValidationError{Path: path, Message: fmt.Sprintf("undefined variable '%s'", ref.Name)})
+	})
+	return res
+}
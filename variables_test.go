@@ -0,0 +1,64 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestVarRefRoundTrip(t *testing.T) {
+	ws := &Workspace{Variables: map[string]string{"product": "wdydoc"}}
+	ws.NewDocument().Add(Var("product"))
+	res := roundTrip(t, ws).(*Workspace)
+	if !reflect.DeepEqual(res.Variables, ws.Variables) {
+		t.Fatalf("expected %v but got %v", ws.Variables, res.Variables)
+	}
+	if ref, ok := res.Documents()[0].Body[0].(*VarRef); !ok || ref.Name != "product" {
+		t.Fatalf("unexpected %+v", res.Documents()[0].Body[0])
+	}
+}
+
+func TestResolveVariables(t *testing.T) {
+	tplDir := writeFiles(t, map[string]string{"out.txt.tmpl": "{{range .Body}}{{.String}}{{end}}"})
+	ws := &Workspace{Variables: map[string]string{"product": "wdydoc", "version": "1.2"}}
+	doc := ws.NewDocument()
+	doc.Id = "doc"
+	doc.Add(Var("product"), Text(" "), Var("version"), Text(" "), Var("missing"))
+
+	build, err := NewBuild(ws, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	build.AddRule(&BuildRule{Id: "doc", Template: tplDir, Name: "out"})
+	files, err := build.BuildToMemory(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(files["out/out.txt"]); got != "wdydoc 1.2 [undefined variable missing]" {
+		t.Fatal(got)
+	}
+
+	build.StrictVariables = true
+	if _, err := build.BuildToMemory(context.Background()); err == nil {
+		t.Fatal("expected undefined variable to fail")
+	}
+	if errs := ws.Validate(""); len(errs) != 1 || errs[0].Error() != "doc: undefined variable 'missing'" {
+		t.Fatalf("unexpected %v", errs)
+	}
+}
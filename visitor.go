@@ -0,0 +1,148 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+// A Visitor has a method for each built-in element type. Use Accept to dispatch an element to the according method
+// and embed BaseVisitor to only implement the methods of interest. A Visitor does not descend into children by
+// itself, combine it with Walk to visit a whole tree. It is an extension point for external code, the built-in
+// renderers still switch over the types themselves.
+type Visitor interface {
+	VisitWorkspace(w *Workspace)
+	VisitDocument(d *Document)
+	VisitAuthor(a *Author)
+	VisitPart(p *Part)
+	VisitChapter(c *Chapter)
+	VisitSpan(s *Span)
+	VisitVarRef(v *VarRef)
+	VisitCode(c *Code)
+	VisitDiff(d *Diff)
+	VisitImage(i *Image)
+	VisitList(l *List)
+	VisitListEntry(e *ListEntry)
+	VisitAlign(a *Align)
+	VisitAttachment(a *Attachment)
+	VisitEmbed(e *Embed)
+	VisitAbbreviation(a *Abbreviation)
+	VisitRevisionHistory(h *RevisionHistory)
+	VisitLink(l *Hyperlink)
+	VisitTooltip(t *Tooltip)
+	VisitTable(t *Table)
+	VisitTableRow(r *TableRow)
+	VisitTableCell(c *TableCell)
+	VisitMarginNote(n *MarginNote)
+	VisitTitlepage(t *Titlepage)
+
+	// VisitGroup is called for formatting groups like Bold, Italic or Underline
+	VisitGroup(d Discriminator, body []Discriminator)
+
+	// VisitMarker is called for elements without content like LineBreak, Newpage or TOC
+	VisitMarker(d Discriminator)
+
+	// VisitOther is called for any element of an unknown type
+	VisitOther(d Discriminator)
+}
+
+// Accept calls the method of the visitor, which belongs to the concrete type of the element.
+func Accept(d Discriminator, v Visitor) {
+	switch t := d.(type) {
+	case *Workspace:
+		v.VisitWorkspace(t)
+	case *Document:
+		v.VisitDocument(t)
+	case *Author:
+		v.VisitAuthor(t)
+	case *Part:
+		v.VisitPart(t)
+	case *Chapter:
+		v.VisitChapter(t)
+	case *Span:
+		v.VisitSpan(t)
+	case *VarRef:
+		v.VisitVarRef(t)
+	case *Code:
+		v.VisitCode(t)
+	case *Diff:
+		v.VisitDiff(t)
+	case *Image:
+		v.VisitImage(t)
+	case *List:
+		v.VisitList(t)
+	case *ListEntry:
+		v.VisitListEntry(t)
+	case *Align:
+		v.VisitAlign(t)
+	case *Attachment:
+		v.VisitAttachment(t)
+	case *Embed:
+		v.VisitEmbed(t)
+	case *Abbreviation:
+		v.VisitAbbreviation(t)
+	case *RevisionHistory:
+		v.VisitRevisionHistory(t)
+	case *Hyperlink:
+		v.VisitLink(t)
+	case *Tooltip:
+		v.VisitTooltip(t)
+	case *Table:
+		v.VisitTable(t)
+	case *TableRow:
+		v.VisitTableRow(t)
+	case *TableCell:
+		v.VisitTableCell(t)
+	case *defaultBody:
+		v.VisitGroup(t, t.Body)
+	case *MarginNote:
+		v.VisitMarginNote(t)
+	case *Titlepage:
+		v.VisitTitlepage(t)
+	case defaultType:
+		v.VisitMarker(t)
+	default:
+		v.VisitOther(d)
+	}
+}
+
+// BaseVisitor implements all methods of Visitor without doing anything.
+type BaseVisitor struct{}
+
+func (BaseVisitor) VisitWorkspace(*Workspace)                 {}
+func (BaseVisitor) VisitDocument(*Document)                   {}
+func (BaseVisitor) VisitAuthor(*Author)                       {}
+func (BaseVisitor) VisitPart(*Part)                           {}
+func (BaseVisitor) VisitChapter(*Chapter)                     {}
+func (BaseVisitor) VisitSpan(*Span)                           {}
+func (BaseVisitor) VisitVarRef(*VarRef)                       {}
+func (BaseVisitor) VisitCode(*Code)                           {}
+func (BaseVisitor) VisitDiff(*Diff)                           {}
+func (BaseVisitor) VisitImage(*Image)                         {}
+func (BaseVisitor) VisitList(*List)                           {}
+func (BaseVisitor) VisitListEntry(*ListEntry)                 {}
+func (BaseVisitor) VisitAlign(*Align)                         {}
+func (BaseVisitor) VisitAttachment(*Attachment)               {}
+func (BaseVisitor) VisitEmbed(*Embed)                         {}
+func (BaseVisitor) VisitAbbreviation(*Abbreviation)           {}
+func (BaseVisitor) VisitRevisionHistory(*RevisionHistory)     {}
+func (BaseVisitor) VisitLink(*Hyperlink)                      {}
+func (BaseVisitor) VisitTooltip(*Tooltip)                     {}
+func (BaseVisitor) VisitTable(*Table)                         {}
+func (BaseVisitor) VisitTableRow(*TableRow)                   {}
+func (BaseVisitor) VisitTableCell(*TableCell)                 {}
+func (BaseVisitor) VisitMarginNote(*MarginNote)               {}
+func (BaseVisitor) VisitTitlepage(*Titlepage)                 {}
+func (BaseVisitor) VisitGroup(Discriminator, []Discriminator) {}
+func (BaseVisitor) VisitMarker(Discriminator)                 {}
+func (BaseVisitor) VisitOther(Discriminator)                  {}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"testing"
+)
+
+type spanCounter struct {
+	BaseVisitor
+	spans      int
+	markers    int
+	titlepages int
+	notes      int
+}
+
+func (c *spanCounter) VisitSpan(*Span) {
+	c.spans++
+}
+
+func (c *spanCounter) VisitMarker(Discriminator) {
+	c.markers++
+}
+
+func (c *spanCounter) VisitTitlepage(*Titlepage) {
+	c.titlepages++
+}
+
+func (c *spanCounter) VisitMarginNote(*MarginNote) {
+	c.notes++
+}
+
+func TestVisitor(t *testing.T) {
+	model := createModel(t)
+	counter := &spanCounter{}
+	Walk(model, func(d Discriminator) bool {
+		Accept(d, counter)
+		return true
+	})
+	if want := len(Collect(model, TextType)); counter.spans != want || want == 0 {
+		t.Fatalf("expected %d spans but got %d", want, counter.spans)
+	}
+	if want := len(Collect(model, LineBreakType)) + len(Collect(model, TOCType)); counter.markers != want {
+		t.Fatalf("expected %d markers but got %d", want, counter.markers)
+	}
+	if want := len(Collect(model, TitlepageType)); counter.titlepages != want || want == 0 {
+		t.Fatalf("expected %d title pages but got %d", want, counter.titlepages)
+	}
+
+	Accept(Margin(Text("note")), counter)
+	if counter.notes != 1 {
+		t.Fatalf("expected a margin note but got %d", counter.notes)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Walk visits the element and all of its children in document order, which is a depth-first pre-order traversal.
+// If f returns false, the children of the element are skipped. An element which contains itself is not walked
+// again, so cyclic transclusions terminate.
+func Walk(d Discriminator, f func(d Discriminator) bool) {
+	walk(d, f, make(map[uintptr]bool))
+}
+
+func walk(d Discriminator, f func(d Discriminator) bool, path map[uintptr]bool) {
+	key, isPtr := identity(d)
+	if isPtr {
+		if path[key] {
+			return
+		}
+		path[key] = true
+		defer delete(path, key)
+	}
+	if !f(d) {
+		return
+	}
+	if c, ok := d.(container); ok {
+		for _, child := range c.children() {
+			walk(child, f, path)
+		}
+	}
+}
+
+// walkPath is like Walk but also provides the location of each element, which is made of the document ids (or
+// titles) and chapter titles, e.g. 1234/my first chapter/a section.
+func walkPath(d Discriminator, f func(d Discriminator, path string)) {
+	walkPathDepth(d, func(d Discriminator, path string, depth int) {
+		f(d, path)
+	})
+}
+
+// walkPathDepth is like walkPath but also provides the nesting depth of each element, starting at 0.
+func walkPathDepth(d Discriminator, f func(d Discriminator, path string, depth int)) {
+	var path []string
+	var visit func(d Discriminator, depth int, onPath map[uintptr]bool)
+	visit = func(d Discriminator, depth int, onPath map[uintptr]bool) {
+		key, isPtr := identity(d)
+		if isPtr {
+			if onPath[key] {
+				return
+			}
+			onPath[key] = true
+			defer delete(onPath, key)
+		}
+		name := ""
+		switch t := d.(type) {
+		case *Document:
+			name = t.Id
+			if name == "" {
+				name = t.Title
+			}
+		case *Part:
+			name = t.Title
+		case *Chapter:
+			name = t.Title
+		}
+		if name != "" {
+			path = append(path, name)
+			defer func() {
+				path = path[:len(path)-1]
+			}()
+		}
+		f(d, strings.Join(path, "/"), depth)
+		if c, ok := d.(container); ok {
+			for _, child := range c.children() {
+				visit(child, depth+1, onPath)
+			}
+		}
+	}
+	visit(d, 0, make(map[uintptr]bool))
+}
+
+// Query returns all elements of the tree, for which the predicate is true. The elements are in document order
+// (see Walk) and the same pointer is only returned once, at the position of its first occurrence.
+func Query(d Discriminator, predicate func(d Discriminator) bool) []Discriminator {
+	var res []Discriminator
+	seen := make(map[uintptr]bool)
+	Walk(d, func(d Discriminator) bool {
+		if key, isPtr := identity(d); isPtr {
+			if seen[key] {
+				return false
+			}
+			seen[key] = true
+		}
+		if predicate(d) {
+			res = append(res, d)
+		}
+		return true
+	})
+	return res
+}
+
+// Collect returns all elements of the given type, with the same guarantees as Query.
+func Collect(d Discriminator, typeName string) []Discriminator {
+	return Query(d, func(d Discriminator) bool {
+		return d.Type() == typeName
+	})
+}
+
+// identity returns the pointer of the element, if it has any
+func identity(d Discriminator) (uintptr, bool) {
+	v := reflect.ValueOf(d)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return 0, false
+	}
+	return v.Pointer(), true
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func titlesOf(elems []Discriminator) []string {
+	var res []string
+	for _, e := range elems {
+		res = append(res, e.(*Chapter).Title)
+	}
+	return res
+}
+
+func TestCollectOrder(t *testing.T) {
+	ws := createModel(t)
+	want := []string{"my first chapter", "a section", "a subsection", "another main chapter"}
+	for i := 0; i < 10; i++ {
+		if got := titlesOf(Collect(ws, ChapterType)); !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: expected %v but got %v", i, want, got)
+		}
+	}
+
+	spans := Query(ws, func(d Discriminator) bool {
+		span, ok := d.(*Span)
+		return ok && span.Value == "d"
+	})
+	if len(spans) != 1 {
+		t.Fatalf("expected a single span but got %d", len(spans))
+	}
+}
+
+func TestCollectShared(t *testing.T) {
+	shared := &Chapter{Title: "shared"}
+	shared.NewChapter("child")
+	doc := &Document{}
+	doc.NewChapter("first").Add(shared)
+	doc.Add(shared)
+	doc.NewChapter("last")
+
+	want := []string{"first", "shared", "child", "last"}
+	if got := titlesOf(Collect(doc, ChapterType)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+
+	// a cycle must terminate
+	shared.Add(shared)
+	if got := titlesOf(Collect(doc, ChapterType)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is the quiet period after the last file change, before a rebuild is started.
+const watchDebounce = 200 * time.Millisecond
+
+// Watch builds once and rebuilds whenever the InputFile or a file of a local template changes, until the context
+// is cancelled. Remote git templates are not watched. A changed InputFile is reloaded before rebuilding. Failed
+// builds are reported using OnEvent and do not stop watching.
+func (b *Build) Watch(ctx context.Context) error {
+	return b.WatchFunc(ctx, b.ApplyContext)
+}
+
+// WatchFunc is like Watch but calls rebuild instead of ApplyContext, e.g. to use BuildToMemory. An error of rebuild
+// does not stop watching.
+func (b *Build) WatchFunc(ctx context.Context, rebuild func(ctx context.Context) error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("cannot create file watcher: %w", err)
+	}
+	defer func() {
+		err := watcher.Close()
+		if err != nil {
+			fmt.Printf("failed to close file watcher: %v\n", err)
+		}
+	}()
+
+	if b.InputFile != "" {
+		// editors often replace files instead of writing them, so watch the parent folder
+		if err := watcher.Add(filepath.Dir(b.InputFile)); err != nil {
+			return fmt.Errorf("cannot watch %s: %w", b.InputFile, err)
+		}
+	}
+	for _, r := range b.rules {
+		if isUrl(r.Template) {
+			continue
+		}
+		if err := watchDirs(watcher, r.Template); err != nil {
+			return err
+		}
+	}
+
+	_ = rebuild(ctx)
+
+	var timer <-chan time.Time
+	inputChanged := false
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-watcher.Errors:
+			return fmt.Errorf("failed to watch files: %w", err)
+		case e := <-watcher.Events:
+			switch {
+			case b.InputFile != "" && filepath.Clean(e.Name) == filepath.Clean(b.InputFile):
+				inputChanged = true
+			case !b.isLocalTemplateFile(e.Name):
+				continue
+			}
+			if e.Op&fsnotify.Create != 0 && IsDir(e.Name) {
+				_ = watchDirs(watcher, e.Name)
+			}
+			timer = time.After(watchDebounce)
+		case <-timer:
+			timer = nil
+			if inputChanged {
+				inputChanged = false
+				w, err := unmarshalInputFile(b.InputFile)
+				if err != nil {
+					b.fire(BuildEvent{Kind: EventFailed, Err: err})
+					continue
+				}
+				b.workspace = w
+			}
+			_ = rebuild(ctx)
+		}
+	}
+}
+
+// isLocalTemplateFile checks if the path is located within any local template folder.
+func (b *Build) isLocalTemplateFile(path string) bool {
+	for _, r := range b.rules {
+		if isUrl(r.Template) {
+			continue
+		}
+		rel, err := filepath.Rel(r.Template, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchDirs adds dir and all of its non-hidden sub directories to the watcher.
+func watchDirs(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to walk path %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != dir && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("cannot watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wideRanges are the east asian wide and fullwidth code points, which occupy two columns of a terminal
+var wideRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe4f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// DisplayWidth returns the amount of columns, which the text occupies in a monospaced output. East asian wide
+// characters, like CJK, count twice and combining marks, zero width and control characters do not count at all.
+func DisplayWidth(str string) int {
+	width := 0
+	for _, r := range str {
+		switch {
+		case r == 0x200b || r == 0x200c || r == 0x200d || r == 0xfeff:
+		case unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me):
+		case unicode.Is(wideRanges, r):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// padRight appends spaces, until the text occupies width columns
+func padRight(str string, width int) string {
+	if n := width - DisplayWidth(str); n > 0 {
+		return str + strings.Repeat(" ", n)
+	}
+	return str
+}
+
+// alignText pads the text with spaces on the left, the right or both sides, until it occupies width columns
+func alignText(str string, width int, align string) string {
+	n := width - DisplayWidth(str)
+	if n <= 0 {
+		return str
+	}
+	switch align {
+	case AlignRight:
+		return strings.Repeat(" ", n) + str
+	case AlignCenter:
+		return strings.Repeat(" ", n/2) + str + strings.Repeat(" ", n-n/2)
+	default:
+		return padRight(str, width)
+	}
+}
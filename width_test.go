@@ -0,0 +1,46 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	for str, want := range map[string]int{
+		"abc":        3,
+		"日本語":        6,
+		"한국어":        6,
+		"e\u0301":    1,
+		"ｆｕｌｌ":       8,
+		"a\u200bb":   2,
+		"mixed 文字 x": 12,
+	} {
+		if got := DisplayWidth(str); got != want {
+			t.Fatalf("expected %d columns for %q but got %d", want, str, got)
+		}
+	}
+
+	rows := [][]string{{"name", "value"}, {"日本", "1"}, {"café", "2"}}
+	var lines []string
+	for _, row := range rows {
+		lines = append(lines, "|"+padRight(row[0], 6)+"|"+padRight(row[1], 5)+"|")
+	}
+	for _, line := range lines {
+		if DisplayWidth(line) != 14 {
+			t.Fatalf("misaligned column in %q", line)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright 2020 Torben Schinke
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package wdydoc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalYAML(t *testing.T) {
+	markup := `{"type":"workspace","title":"handbook","version":"1.0","resources":[
+		{"type":"document","id":"doc","title":"User's Guide","numberDepth":2,
+		 "authors":[{"type":"author","firstname":"Ada","lastname":"Lovelace","email":"ada@example.com"}],
+		 "body":[
+			{"type":"chapter","title":"Intro: why?","level":1,"body":[
+				{"type":"text","value":"first paragraph\nsecond line\n"},
+				{"type":"bold","body":[{"type":"text","value":"strong # not a comment"}]}
+			]},
+			{"type":"code","hint":"go","lines":["package main","","func main() {}"]},
+			{"type":"list","ordered":true,"start":3,"items":[
+				{"type":"listitem","checked":false,"body":[{"type":"text","value":"a"}]},
+				{"type":"listitem","body":[{"type":"text","value":"b c"}]}
+			]}
+		 ]}
+	]}`
+
+	yaml := `# a hand written workspace
+type: workspace
+title: handbook
+version: "1.0"
+resources:
+- type: document
+  id: doc
+  title: 'User''s Guide'
+  numberDepth: 2
+  authors:
+    - {type: author, firstname: Ada, lastname: Lovelace, email: ada@example.com}
+  body:
+    - type: chapter
+      title: "Intro: why?"
+      level: 1
+      body:
+        - type: text
+          value: |
+            first paragraph
+            second line
+        - type: bold # formatting
+          body: [{type: text, value: "strong # not a comment"}]
+    - type: code
+      hint: go
+      lines:
+        - package main
+        - ""
+        - func main() {}
+    - type: list
+      ordered: true
+      start: 3
+      items:
+        - type: listitem
+          checked: false
+          body:
+            - {type: text, value: a}
+        - type: listitem
+          body:
+            - type: text
+              value: >-
+                b
+                c
+`
+
+	want, err := Unmarshal([]byte(markup))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalYAML([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("expected\n%s\nbut got\n%s", debugJson(want), debugJson(got))
+	}
+}
+
+func TestUnmarshalYAMLErrors(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want string
+	}{
+		{"- a\n- b\n", "expected a mapping"},
+		{"type: workspace\nresources: [{type: bogus}]\n", "unknown type 'bogus'"},
+		{"type: workspace\n\ttitle: x\n", "yaml: line 2"},
+		{"type: workspace\ntitle: [a, b\n", "did not find expected ',' or ']'"},
+	}
+	for _, tt := range tests {
+		_, err := UnmarshalYAML([]byte(tt.yaml))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%q: expected error containing '%s' but got %v", tt.yaml, tt.want, err)
+		}
+	}
+}
+
+func TestUnmarshalYAMLSyntax(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want string
+	}{
+		{"type: workspace\ntitle: |\n  func main() {\n  \tprintln()\n  }\n", "func main() {\n\tprintln()\n}\n"},
+		{"type: workspace\ntitle: multi\nresources: [\n  {type: document, title: flow},\n]\n", "multi"},
+		{"type: workspace\ntitle: a plain\n  continued title\n", "a plain continued title"},
+	}
+	for _, tt := range tests {
+		ws, err := UnmarshalYAML([]byte(tt.yaml))
+		if err != nil {
+			t.Fatalf("%q: %v", tt.yaml, err)
+		}
+		if ws.Title != tt.want {
+			t.Fatalf("%q: expected title %q but got %q", tt.yaml, tt.want, ws.Title)
+		}
+	}
+
+	ws, err := UnmarshalYAML([]byte("type: workspace\nresources: [\n  {type: document, title: flow},\n]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs := ws.Documents(); len(docs) != 1 || docs[0].Title != "flow" {
+		t.Fatalf("expected a single document but got %v", docs)
+	}
+}
+
+func TestUnmarshalYAMLScalars(t *testing.T) {
+	yaml := `type: workspace
+title: 2020
+version: 1.0
+resources:
+- type: document
+  id: doc
+  body:
+  - type: revisions
+    entries:
+    - {version: 1.0, date: 2020-03-01}
+  - type: chapter
+    title: 3.14
+    level: 2
+`
+	w, err := UnmarshalYAML([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Title != "2020" || w.Version != "1.0" {
+		t.Fatalf("expected title 2020 and version 1.0 but got '%s' and '%s'", w.Title, w.Version)
+	}
+	doc := w.Resources[0].(*Document)
+	rev := doc.Body[0].(*RevisionHistory).Entries[0]
+	if rev.Version != "1.0" || rev.Date != "2020-03-01" {
+		t.Fatalf("expected version 1.0 from 2020-03-01 but got %+v", rev)
+	}
+	chapter := doc.Body[1].(*Chapter)
+	if chapter.Title != "3.14" || chapter.Level != 2 {
+		t.Fatalf("expected chapter 3.14 at level 2 but got '%s' at %d", chapter.Title, chapter.Level)
+	}
+}